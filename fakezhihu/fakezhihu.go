@@ -0,0 +1,986 @@
+// Package fakezhihu 提供一个基于 httptest 的假知乎服务,
+// 用合成的关注关系和话题树实现采集器用到的接口, 以便离线测试.
+package fakezhihu
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultUsers     = 50
+	defaultFollows   = 5
+	defaultTopics    = 40
+	defaultChildren  = 3
+	defaultPageSize  = 20
+	defaultQuestions = 10
+	defaultAnswers   = 5
+
+	// 每个话题下的问题数量
+	topicQuestions = 3
+	// 每次搜索返回的用户和话题数量
+	searchResults = 5
+
+	topicIDBase    = 19776749
+	questionIDBase = 20000000
+	answerIDBase   = 100000000
+	articleIDBase  = 300000000
+
+	// 每 columnOwnerEvery 个用户中的第一个拥有一个专栏, 其他人是专栏的投稿人
+	columnOwnerEvery = 5
+)
+
+// Config 描述合成数据的规模, 分页和错误注入.
+type Config struct {
+	// Users 用户数量
+	Users int
+	// Follows 每个用户关注的人数
+	Follows int
+	// Topics 话题数量, 0 号话题为根话题
+	Topics int
+	// Children 每个话题的子话题数量
+	Children int
+	// PageSize 未指定 limit 时每页的数量
+	PageSize int
+	// Questions 问题数量, Answers 每个问题的回答数量
+	Questions int
+	Answers   int
+	// Robots 为 /robots.txt 的内容, 为空时返回 404
+	Robots string
+	// RobotsErrors 前几次请求 /robots.txt 时返回 503
+	RobotsErrors int
+	// DisableTopicAPI 为 true 时话题接口返回 500, 用于测试使用页面采集
+	DisableTopicAPI bool
+
+	// ErrorRate 每个请求返回错误的概率
+	ErrorRate float64
+	// ErrorStatus 错误响应的状态码, 为 0 时返回 200 和验证页面
+	ErrorStatus int
+
+	Seed int64
+}
+
+func (c *Config) setDefault() {
+	if c.Users <= 0 {
+		c.Users = defaultUsers
+	}
+	if c.Follows <= 0 {
+		c.Follows = defaultFollows
+	}
+	if c.Follows >= c.Users {
+		c.Follows = c.Users - 1
+	}
+	if c.Topics <= 0 {
+		c.Topics = defaultTopics
+	}
+	if c.Children <= 0 {
+		c.Children = defaultChildren
+	}
+	if c.PageSize <= 0 {
+		c.PageSize = defaultPageSize
+	}
+	if c.Questions <= 0 {
+		c.Questions = defaultQuestions
+	}
+	if c.Answers <= 0 {
+		c.Answers = defaultAnswers
+	}
+}
+
+// NewServer 生成合成数据并启动服务, 使用完需要调用 Close.
+func NewServer(config *Config) *Server {
+	if config == nil {
+		config = &Config{}
+	}
+	c := *config
+	c.setDefault()
+
+	s := &Server{
+		config:    c,
+		rand:      rand.New(rand.NewSource(c.Seed)),
+		followees: make([][]int, c.Users),
+		followers: make([][]int, c.Users),
+	}
+	s.buildGraph()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v4/members/", s.handleMember)
+	mux.HandleFunc("/api/v3/topics/", s.handleTopicChildren)
+	mux.HandleFunc("/api/v4/topics/", s.handleTopic)
+	mux.HandleFunc("/api/v4/questions/", s.handleQuestionAnswers)
+	mux.HandleFunc("/api/v4/columns/", s.handleColumnArticles)
+	mux.HandleFunc("/api/v4/answers/", s.handleRootComments)
+	mux.HandleFunc("/api/v4/articles/", s.handleRootComments)
+	mux.HandleFunc("/api/v4/comments/", s.handleChildComments)
+	mux.HandleFunc("/api/v3/feed/topstory/hot-lists/total", s.handleHotList)
+	mux.HandleFunc("/api/v4/search_v3", s.handleSearch)
+	mux.HandleFunc("/topic/", s.handleTopicPage)
+	mux.HandleFunc("/people/", s.handlePeoplePage)
+	mux.HandleFunc("/robots.txt", s.handleRobots)
+	s.Server = httptest.NewServer(s.countRequest(mux))
+	return s
+}
+
+type Server struct {
+	*httptest.Server
+
+	config Config
+
+	mutex    sync.Mutex
+	rand     *rand.Rand
+	requests int
+	// robotsAgents 是请求 /robots.txt 时的 User-Agent
+	robotsAgents []string
+
+	followees [][]int
+	followers [][]int
+}
+
+// URLToken 返回第 i 个用户的 url token
+func (s *Server) URLToken(i int) string {
+	return fmt.Sprintf("user-%d", i)
+}
+
+// TopicID 返回第 i 个话题的 id
+func (s *Server) TopicID(i int) string {
+	return strconv.Itoa(topicIDBase + i)
+}
+
+// Followees 返回第 i 个用户关注的人
+func (s *Server) Followees(i int) []string {
+	return s.urlTokens(s.followees[i])
+}
+
+// Followers 返回关注第 i 个用户的人
+func (s *Server) Followers(i int) []string {
+	return s.urlTokens(s.followers[i])
+}
+
+// Children 返回第 i 个话题的子话题 id
+func (s *Server) Children(i int) []string {
+	var ids []string
+	for _, child := range s.children(i) {
+		ids = append(ids, s.TopicID(child))
+	}
+	return ids
+}
+
+// Requests 返回目前为止收到的请求数
+func (s *Server) Requests() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.requests
+}
+
+func (s *Server) buildGraph() {
+	users := s.config.Users
+	for i := 0; i < users; i++ {
+		seen := map[int]bool{i: true}
+		for len(s.followees[i]) < s.config.Follows {
+			j := s.rand.Intn(users)
+			if seen[j] {
+				continue
+			}
+			seen[j] = true
+			s.followees[i] = append(s.followees[i], j)
+			s.followers[j] = append(s.followers[j], i)
+		}
+	}
+}
+
+func (s *Server) urlTokens(users []int) []string {
+	var urlTokens []string
+	for _, i := range users {
+		urlTokens = append(urlTokens, s.URLToken(i))
+	}
+	return urlTokens
+}
+
+func (s *Server) children(i int) []int {
+	var children []int
+	for j := 1; j <= s.config.Children; j++ {
+		child := i*s.config.Children + j
+		if child >= s.config.Topics {
+			break
+		}
+		children = append(children, child)
+	}
+	return children
+}
+
+func (s *Server) userIndex(urlToken string) (int, bool) {
+	if !strings.HasPrefix(urlToken, "user-") {
+		return 0, false
+	}
+	i, err := strconv.Atoi(strings.TrimPrefix(urlToken, "user-"))
+	if err != nil || i < 0 || i >= s.config.Users {
+		return 0, false
+	}
+	return i, true
+}
+
+func (s *Server) topicIndex(topicID string) (int, bool) {
+	id, err := strconv.Atoi(topicID)
+	if err != nil {
+		return 0, false
+	}
+	i := id - topicIDBase
+	if i < 0 || i >= s.config.Topics {
+		return 0, false
+	}
+	return i, true
+}
+
+func (s *Server) countRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mutex.Lock()
+		s.requests++
+		injectError := s.config.ErrorRate > 0 && s.rand.Float64() < s.config.ErrorRate
+		s.mutex.Unlock()
+
+		if injectError {
+			s.writeError(w)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) writeError(w http.ResponseWriter) {
+	if s.config.ErrorStatus == 0 {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, "<html><body>请输入验证码</body></html>")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(s.config.ErrorStatus)
+	fmt.Fprintf(w, `{"error":{"code":%d,"message":"%s","name":"FakeError"}}`,
+		s.config.ErrorStatus, http.StatusText(s.config.ErrorStatus))
+}
+
+// handleMember 处理 /api/v4/members/{urlToken}/followees, followers, answers,
+// articles 和 column-contributions
+func (s *Server) handleMember(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v4/members/"), "/")
+	if len(parts) != 2 {
+		http.NotFound(w, r)
+		return
+	}
+
+	i, ok := s.userIndex(parts[0])
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch parts[1] {
+	case "answers":
+		s.handleMemberAnswers(w, r, i)
+		return
+	case "articles":
+		s.handleMemberArticles(w, r, i)
+		return
+	case "column-contributions":
+		s.handleMemberColumns(w, r, i)
+		return
+	}
+
+	var users []int
+	switch parts[1] {
+	case "followees":
+		users = s.followees[i]
+	case "followers":
+		users = s.followers[i]
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	offset, limit := s.paging(r)
+	var data []interface{}
+	for _, j := range page(len(users), offset, limit) {
+		u := users[j]
+		data = append(data, map[string]interface{}{
+			"id":             strconv.Itoa(u),
+			"url_token":      s.URLToken(u),
+			"name":           s.URLToken(u),
+			"type":           "people",
+			"is_org":         u%10 == 0,
+			"follower_count": len(s.followers[u]),
+			"answer_count":   u,
+			"articles_count": u % 7,
+		})
+	}
+	s.writePaging(w, r, offset, limit, len(users), data)
+}
+
+// handleTopicChildren 处理 /api/v3/topics/{topicID}/children
+func (s *Server) handleTopicChildren(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v3/topics/"), "/")
+	if len(parts) != 2 || parts[1] != "children" {
+		http.NotFound(w, r)
+		return
+	}
+
+	i, ok := s.topicIndex(parts[0])
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	children := s.children(i)
+	offset, limit := s.paging(r)
+	var data []interface{}
+	for _, j := range page(len(children), offset, limit) {
+		child := children[j]
+		data = append(data, map[string]interface{}{
+			"id":   s.TopicID(child),
+			"name": fmt.Sprintf("topic-%d", child),
+			"type": "topic",
+			"url":  fmt.Sprintf("http://%s/api/v3/topics/%s", r.Host, s.TopicID(child)),
+		})
+	}
+	s.writePaging(w, r, offset, limit, len(children), data)
+}
+
+// handleTopic 处理 /api/v4/topics/{topicID} 和 /api/v4/topics/{topicID}/feeds/{feed}
+func (s *Server) handleTopic(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v4/topics/"), "/")
+	i, ok := s.topicIndex(parts[0])
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if len(parts) == 3 && parts[1] == "feeds" {
+		s.handleTopicFeed(w, r, i, parts[2])
+		return
+	}
+	if len(parts) != 1 {
+		http.NotFound(w, r)
+		return
+	}
+	if s.config.DisableTopicAPI {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	followerCount, questionCount := s.TopicCounts(i)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":                   s.TopicID(i),
+		"type":                 "topic",
+		"name":                 fmt.Sprintf("topic-%d", i),
+		"introduction":         fmt.Sprintf("introduction of topic-%d", i),
+		"avatar_url":           fmt.Sprintf("http://%s/avatar/%d.jpg", r.Host, i),
+		"followers_count":      followerCount,
+		"questions_count":      questionCount,
+		"best_answerers_count": i,
+	})
+}
+
+// TopicQuestions 返回第 i 个话题下的问题的下标
+func (s *Server) TopicQuestions(i int) []int {
+	var questions []int
+	for k := 0; k < topicQuestions && k < s.config.Questions; k++ {
+		questions = append(questions, (i+k)%s.config.Questions)
+	}
+	return questions
+}
+
+func (s *Server) question(q int) map[string]interface{} {
+	return map[string]interface{}{
+		"id":             questionIDBase + q,
+		"type":           "question",
+		"title":          fmt.Sprintf("question-%d", q),
+		"answer_count":   s.config.Answers,
+		"follower_count": q * 3,
+		"created":        1400000000 + q,
+	}
+}
+
+// handleTopicFeed 中 timeline_question 返回问题, 其他列表返回每个问题的第一个回答, 末尾有一篇文章
+func (s *Server) handleTopicFeed(w http.ResponseWriter, r *http.Request, i int, feed string) {
+	questions := s.TopicQuestions(i)
+	var items []interface{}
+	switch feed {
+	case "timeline_question":
+		for _, q := range questions {
+			items = append(items, s.question(q))
+		}
+	case "top_activity", "essence":
+		for _, q := range questions {
+			answer := s.answer(q, 0)
+			answer["question"] = s.question(q)
+			items = append(items, answer)
+		}
+		items = append(items, map[string]interface{}{
+			"id":    articleIDBase - 1,
+			"type":  "article",
+			"title": "article in topic feed",
+		})
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	offset, limit := s.paging(r)
+	var data []interface{}
+	for _, j := range page(len(items), offset, limit) {
+		data = append(data, map[string]interface{}{
+			"type":   "topic_feed",
+			"target": items[j],
+		})
+	}
+	s.writePaging(w, r, offset, limit, len(items), data)
+}
+
+// QuestionID 返回第 i 个问题的 id
+func (s *Server) QuestionID(i int) string {
+	return strconv.Itoa(questionIDBase + i)
+}
+
+// AnswerIDs 返回第 i 个问题的所有回答的 id
+func (s *Server) AnswerIDs(i int) []string {
+	var ids []string
+	for j := 0; j < s.config.Answers; j++ {
+		ids = append(ids, strconv.Itoa(s.answerID(i, j)))
+	}
+	return ids
+}
+
+func (s *Server) answerID(question, j int) int {
+	return answerIDBase + question*s.config.Answers + j
+}
+
+// answerAuthor 返回第 question 个问题的第 j 个回答的作者
+func (s *Server) answerAuthor(question, j int) int {
+	return (question*s.config.Answers + j) % s.config.Users
+}
+
+func (s *Server) questionIndex(questionID string) (int, bool) {
+	id, err := strconv.Atoi(questionID)
+	if err != nil {
+		return 0, false
+	}
+	i := id - questionIDBase
+	if i < 0 || i >= s.config.Questions {
+		return 0, false
+	}
+	return i, true
+}
+
+func (s *Server) answer(question, j int) map[string]interface{} {
+	id := s.answerID(question, j)
+	author := s.answerAuthor(question, j)
+	return map[string]interface{}{
+		"id":   id,
+		"type": "answer",
+		"question": map[string]interface{}{
+			"id":    questionIDBase + question,
+			"type":  "question",
+			"title": fmt.Sprintf("question-%d", question),
+		},
+		"author": map[string]interface{}{
+			"id":        strconv.Itoa(author),
+			"url_token": s.URLToken(author),
+			"name":      s.URLToken(author),
+		},
+		"voteup_count":  id % 100,
+		"comment_count": id % 10,
+		"created_time":  1500000000 + id,
+		"updated_time":  1600000000 + id,
+		"content":       fmt.Sprintf("<p>answer-%d</p>", id),
+	}
+}
+
+// memberAnswers 返回第 i 个用户的所有回答的 (问题, 回答) 下标
+func (s *Server) memberAnswers(i int) [][2]int {
+	var answers [][2]int
+	for q := 0; q < s.config.Questions; q++ {
+		for j := 0; j < s.config.Answers; j++ {
+			if s.answerAuthor(q, j) == i {
+				answers = append(answers, [2]int{q, j})
+			}
+		}
+	}
+	return answers
+}
+
+// MemberAnswerIDs 返回第 i 个用户的所有回答的 id
+func (s *Server) MemberAnswerIDs(i int) []string {
+	var ids []string
+	for _, a := range s.memberAnswers(i) {
+		ids = append(ids, strconv.Itoa(s.answerID(a[0], a[1])))
+	}
+	return ids
+}
+
+func (s *Server) handleMemberAnswers(w http.ResponseWriter, r *http.Request, i int) {
+	answers := s.memberAnswers(i)
+	offset, limit := s.paging(r)
+	var data []interface{}
+	for _, j := range page(len(answers), offset, limit) {
+		data = append(data, s.answer(answers[j][0], answers[j][1]))
+	}
+	s.writePaging(w, r, offset, limit, len(answers), data)
+}
+
+// handleQuestionAnswers 处理 /api/v4/questions/{questionID}/answers
+func (s *Server) handleQuestionAnswers(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v4/questions/"), "/")
+	i, ok := s.questionIndex(parts[0])
+	if len(parts) != 2 || parts[1] != "answers" || !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	offset, limit := s.paging(r)
+	var data []interface{}
+	for _, j := range page(s.config.Answers, offset, limit) {
+		data = append(data, s.answer(i, j))
+	}
+	s.writePaging(w, r, offset, limit, s.config.Answers, data)
+}
+
+// ColumnID 返回第 i 个用户拥有的专栏的 id, 用户没有专栏时返回空字符串
+func (s *Server) ColumnID(i int) string {
+	if i%columnOwnerEvery != 0 {
+		return ""
+	}
+	return fmt.Sprintf("column-%d", i)
+}
+
+// ArticleIDs 返回第 i 个用户的所有文章的 id, 文章数量与用户的 articles_count 一致
+func (s *Server) ArticleIDs(i int) []string {
+	var ids []string
+	for k := 0; k < i%7; k++ {
+		ids = append(ids, strconv.Itoa(s.articleID(i, k)))
+	}
+	return ids
+}
+
+func (s *Server) articleID(i, k int) int {
+	return articleIDBase + i*7 + k
+}
+
+func (s *Server) columnIndex(columnID string) (int, bool) {
+	if !strings.HasPrefix(columnID, "column-") {
+		return 0, false
+	}
+	i, err := strconv.Atoi(strings.TrimPrefix(columnID, "column-"))
+	if err != nil || i < 0 || i >= s.config.Users || s.ColumnID(i) == "" {
+		return 0, false
+	}
+	return i, true
+}
+
+func (s *Server) column(i int) map[string]interface{} {
+	return map[string]interface{}{
+		"id":             s.ColumnID(i),
+		"type":           "column",
+		"title":          fmt.Sprintf("column-%d", i),
+		"intro":          fmt.Sprintf("intro of column-%d", i),
+		"articles_count": i % 7,
+		"followers":      len(s.followers[i]),
+		"author": map[string]interface{}{
+			"id":        strconv.Itoa(i),
+			"url_token": s.URLToken(i),
+			"name":      s.URLToken(i),
+		},
+	}
+}
+
+func (s *Server) article(i, k int) map[string]interface{} {
+	id := s.articleID(i, k)
+	article := map[string]interface{}{
+		"id":    id,
+		"type":  "article",
+		"title": fmt.Sprintf("article-%d", id),
+		"author": map[string]interface{}{
+			"id":        strconv.Itoa(i),
+			"url_token": s.URLToken(i),
+			"name":      s.URLToken(i),
+		},
+		"voteup_count":  id % 100,
+		"comment_count": id % 10,
+		"created":       1500000000 + id,
+		"updated":       1600000000 + id,
+	}
+	if columnID := s.ColumnID(i); columnID != "" {
+		article["column"] = map[string]interface{}{
+			"id":    columnID,
+			"title": fmt.Sprintf("column-%d", i),
+		}
+	}
+	return article
+}
+
+func (s *Server) handleMemberArticles(w http.ResponseWriter, r *http.Request, i int) {
+	totals := i % 7
+	offset, limit := s.paging(r)
+	var data []interface{}
+	for _, k := range page(totals, offset, limit) {
+		data = append(data, s.article(i, k))
+	}
+	s.writePaging(w, r, offset, limit, totals, data)
+}
+
+// handleMemberColumns 返回用户拥有的专栏, 以及用户投稿的专栏
+func (s *Server) handleMemberColumns(w http.ResponseWriter, r *http.Request, i int) {
+	var contributions []interface{}
+	if s.ColumnID(i) != "" {
+		contributions = append(contributions, map[string]interface{}{
+			"column":          s.column(i),
+			"contribute_type": "own",
+		})
+	} else {
+		contributions = append(contributions, map[string]interface{}{
+			"column":          s.column(i - i%columnOwnerEvery),
+			"contribute_type": "contribute",
+		})
+	}
+
+	offset, limit := s.paging(r)
+	var data []interface{}
+	for _, j := range page(len(contributions), offset, limit) {
+		data = append(data, contributions[j])
+	}
+	s.writePaging(w, r, offset, limit, len(contributions), data)
+}
+
+// handleColumnArticles 处理 /api/v4/columns/{columnID}/items, 列表末尾有一条不是文章的数据
+func (s *Server) handleColumnArticles(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v4/columns/"), "/")
+	i, ok := s.columnIndex(parts[0])
+	if len(parts) != 2 || parts[1] != "items" || !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	totals := i%7 + 1
+	offset, limit := s.paging(r)
+	var data []interface{}
+	for _, k := range page(totals, offset, limit) {
+		if k == totals-1 {
+			data = append(data, map[string]interface{}{
+				"id":   articleIDBase - 1 - i,
+				"type": "zvideo",
+			})
+			continue
+		}
+		data = append(data, s.article(i, k))
+	}
+	s.writePaging(w, r, offset, limit, totals, data)
+}
+
+// RootCommentIDs 返回 id 为 resourceID 的回答或文章的根评论 id, 数量为 resourceID%4
+func (s *Server) RootCommentIDs(resourceID int) []int {
+	var ids []int
+	for k := 0; k < resourceID%4; k++ {
+		ids = append(ids, resourceID*10+k)
+	}
+	return ids
+}
+
+// ChildCommentIDs 返回根评论 commentID 的子评论 id, 数量为 commentID%3
+func (s *Server) ChildCommentIDs(commentID int) []int {
+	var ids []int
+	for k := 0; k < commentID%3; k++ {
+		ids = append(ids, commentID*10+k)
+	}
+	return ids
+}
+
+func (s *Server) comment(id, childCount int, replyTo string) map[string]interface{} {
+	author := id % s.config.Users
+	comment := map[string]interface{}{
+		"id":                  id,
+		"type":                "comment",
+		"content":             fmt.Sprintf("comment-%d", id),
+		"vote_count":          id % 50,
+		"created_time":        1600000000 + id%1000000,
+		"child_comment_count": childCount,
+		"author": map[string]interface{}{
+			"member": map[string]interface{}{
+				"id":        strconv.Itoa(author),
+				"url_token": s.URLToken(author),
+				"name":      s.URLToken(author),
+			},
+		},
+	}
+	if replyTo != "" {
+		comment["reply_to_author"] = map[string]interface{}{
+			"member": map[string]interface{}{
+				"url_token": replyTo,
+				"name":      replyTo,
+			},
+		}
+	}
+	return comment
+}
+
+// handleRootComments 处理 /api/v4/answers/{answerID}/root_comments 和 /api/v4/articles/{articleID}/root_comments
+func (s *Server) handleRootComments(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/api/v4/answers/"), "/api/v4/articles/"), "/")
+	resourceID, err := strconv.Atoi(parts[0])
+	if len(parts) != 2 || parts[1] != "root_comments" || err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	ids := s.RootCommentIDs(resourceID)
+	offset, limit := s.paging(r)
+	var data []interface{}
+	for _, j := range page(len(ids), offset, limit) {
+		data = append(data, s.comment(ids[j], len(s.ChildCommentIDs(ids[j])), ""))
+	}
+	s.writePaging(w, r, offset, limit, len(ids), data)
+}
+
+// handleChildComments 处理 /api/v4/comments/{commentID}/child_comments, 子评论回复根评论的作者
+func (s *Server) handleChildComments(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v4/comments/"), "/")
+	commentID, err := strconv.Atoi(parts[0])
+	if len(parts) != 2 || parts[1] != "child_comments" || err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	ids := s.ChildCommentIDs(commentID)
+	replyTo := s.URLToken(commentID % s.config.Users)
+	offset, limit := s.paging(r)
+	var data []interface{}
+	for _, j := range page(len(ids), offset, limit) {
+		data = append(data, s.comment(ids[j], 0, replyTo))
+	}
+	s.writePaging(w, r, offset, limit, len(ids), data)
+}
+
+// HotListHeat 返回第 q 个问题在热榜中的热度, 单位是万
+func (s *Server) HotListHeat(q int) int {
+	return (s.config.Questions - q) * 100
+}
+
+// handleHotList 按顺序返回所有问题, 第 2 个问题之后有一篇文章
+func (s *Server) handleHotList(w http.ResponseWriter, r *http.Request) {
+	var items []interface{}
+	for q := 0; q < s.config.Questions; q++ {
+		if q == 2 {
+			items = append(items, map[string]interface{}{
+				"type": "hot_list_feed",
+				"target": map[string]interface{}{
+					"id":    articleIDBase - 2,
+					"type":  "article",
+					"title": "article in hot list",
+				},
+				"detail_text": "1 万热度",
+			})
+		}
+		items = append(items, map[string]interface{}{
+			"type":        "hot_list_feed",
+			"target":      s.question(q),
+			"detail_text": fmt.Sprintf("%d 万热度", s.HotListHeat(q)),
+		})
+	}
+
+	offset, limit := s.paging(r)
+	var data []interface{}
+	for _, j := range page(len(items), offset, limit) {
+		data = append(data, items[j])
+	}
+	s.writePaging(w, r, offset, limit, len(items), data)
+}
+
+// handleSearch 处理 /api/v4/search_v3, t=people 返回前 5 个用户, t=topic 返回前 5 个话题,
+// t=general 返回所有问题, 偶数问题以回答的形式出现; 名称中的关键词会被 <em> 包围
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	keyword := "<em>" + r.URL.Query().Get("q") + "</em>"
+
+	var items []interface{}
+	switch r.URL.Query().Get("t") {
+	case "people":
+		for i := 0; i < searchResults && i < s.config.Users; i++ {
+			items = append(items, map[string]interface{}{
+				"id":        fmt.Sprintf("%032x", i),
+				"type":      "people",
+				"url_token": s.URLToken(i),
+				"name":      keyword + s.URLToken(i),
+			})
+		}
+	case "topic":
+		for i := 0; i < searchResults && i < s.config.Topics; i++ {
+			items = append(items, map[string]interface{}{
+				"id":   s.TopicID(i),
+				"type": "topic",
+				"name": fmt.Sprintf("%s topic-%d", keyword, i),
+			})
+		}
+	case "general":
+		for q := 0; q < s.config.Questions; q++ {
+			question := map[string]interface{}{
+				"id":   s.QuestionID(q),
+				"type": "question",
+				"name": fmt.Sprintf("%s question-%d", keyword, q),
+			}
+			if q%2 == 0 {
+				items = append(items, map[string]interface{}{
+					"id":       s.answerID(q, 0),
+					"type":     "answer",
+					"question": question,
+				})
+				continue
+			}
+			items = append(items, question)
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	offset, limit := s.paging(r)
+	var data []interface{}
+	for _, j := range page(len(items), offset, limit) {
+		data = append(data, map[string]interface{}{
+			"type":   "search_result",
+			"object": items[j],
+		})
+	}
+	s.writePaging(w, r, offset, limit, len(items), data)
+}
+
+// handleTopicPage 处理 /topic/{topicID}/hot
+func (s *Server) handleTopicPage(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/topic/"), "/")
+	i, ok := s.topicIndex(parts[0])
+	if len(parts) != 2 || !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	followerCount, questionCount := s.TopicCounts(i)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, `<html><body>
+<div class="NumberBoard"><strong class="NumberBoard-itemValue" title="%d">%d</strong>
+<strong class="NumberBoard-itemValue" title="%d">%d</strong></div>
+</body></html>`, followerCount, followerCount, questionCount, questionCount)
+}
+
+// TopicCounts 返回第 i 个话题页面上的关注者数和问题数
+func (s *Server) TopicCounts(i int) (followerCount, questionCount int) {
+	return (i + 1) * 100, (i + 1) * 10
+}
+
+// handlePeoplePage 处理 /people/{urlToken}/activities
+func (s *Server) handlePeoplePage(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/people/"), "/")
+	i, ok := s.userIndex(parts[0])
+	if len(parts) != 2 || !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	urlToken := s.URLToken(i)
+	user := map[string]interface{}{
+		"urlToken":       urlToken,
+		"name":           urlToken,
+		"headline":       "fake user",
+		"gender":         i % 2,
+		"followingCount": len(s.followees[i]),
+		"followerCount":  len(s.followers[i]),
+		"answerCount":    i,
+		"business":       map[string]string{"name": "互联网"},
+		"locations":      []map[string]string{{"name": "北京"}},
+	}
+	data, _ := json.Marshal(user)
+	// crawlPeople 依靠 selfRecommend 定位用户数据的结尾
+	userJSON := strings.TrimSuffix(string(data), "}") + `,"selfRecommend":null}`
+	script := fmt.Sprintf(`{"initialState":{"entities":{"users":{"%s":%s}}}}`, urlToken, userJSON)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, `<html><body><script id="js-initialData" type="text/json">%s</script></body></html>`, script)
+}
+
+// RobotsAgents 返回每次请求 /robots.txt 时的 User-Agent
+func (s *Server) RobotsAgents() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string(nil), s.robotsAgents...)
+}
+
+func (s *Server) handleRobots(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	s.robotsAgents = append(s.robotsAgents, r.UserAgent())
+	n := len(s.robotsAgents)
+	s.mutex.Unlock()
+
+	if n <= s.config.RobotsErrors {
+		http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	if s.config.Robots == "" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	fmt.Fprint(w, s.config.Robots)
+}
+
+func (s *Server) paging(r *http.Request) (offset, limit int) {
+	query := r.URL.Query()
+	offset, _ = strconv.Atoi(query.Get("offset"))
+	limit, _ = strconv.Atoi(query.Get("limit"))
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 {
+		limit = s.config.PageSize
+	}
+	return offset, limit
+}
+
+// writePaging 和知乎一样, 最后一页之后继续返回空的 data
+func (s *Server) writePaging(w http.ResponseWriter, r *http.Request, offset, limit, totals int, data []interface{}) {
+	if data == nil {
+		data = []interface{}{}
+	}
+
+	pageURL := func(offset int) string {
+		query := r.URL.Query()
+		query.Set("offset", strconv.Itoa(offset))
+		query.Set("limit", strconv.Itoa(limit))
+		return fmt.Sprintf("http://%s%s?%s", r.Host, r.URL.Path, query.Encode())
+	}
+	previous := offset - limit
+	if previous < 0 {
+		previous = 0
+	}
+
+	resp := map[string]interface{}{
+		"paging": map[string]interface{}{
+			"is_end":   offset+limit >= totals,
+			"is_start": offset == 0,
+			"next":     pageURL(offset + limit),
+			"previous": pageURL(previous),
+			"totals":   totals,
+		},
+		"data": data,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// page 返回 [offset, offset+limit) 与 [0, totals) 的交集
+func page(totals, offset, limit int) []int {
+	var indexes []int
+	for i := offset; i < offset+limit && i < totals; i++ {
+		indexes = append(indexes, i)
+	}
+	return indexes
+}
@@ -0,0 +1,9 @@
+-- 定时任务的运行记录, 进程重启后根据最近一次运行判断是否错过了运行时间
+CREATE TABLE IF NOT EXISTS `jobRun` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `name` varchar(64) NOT NULL,
+  `startTime` datetime NOT NULL,
+  `endTime` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_name` (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,17 @@
+-- people 记录采集时间, 每个用户只保留一行, 重新采集时通过唯一索引更新原来的数据.
+-- 如果已有重复的 urlTokenID, 需要先删除旧的行再添加唯一索引.
+ALTER TABLE `people`
+  ADD COLUMN `crawlTime` datetime NULL,
+  ADD UNIQUE KEY `uk_urlTokenID` (`urlTokenID`);
+
+-- urlToken 记录最近一次采集的时间, 粉丝数和失败情况, refreshPeople 按粉丝数查找过期的用户
+ALTER TABLE `urlToken`
+  ADD COLUMN `crawlTime` datetime NULL,
+  ADD COLUMN `followerCount` int unsigned NOT NULL DEFAULT 0,
+  ADD COLUMN `gone` tinyint(1) NOT NULL DEFAULT 0,
+  ADD COLUMN `failTime` datetime NULL,
+  ADD KEY `idx_refresh` (`gone`, `followerCount`);
+
+-- 已有数据没有采集时间, 会在第一次 refreshPeople 时重新采集; 粉丝数从 people 中回填以保持刷新顺序
+UPDATE `urlToken` AS u JOIN `people` AS p ON p.urlTokenID=u.id
+SET u.followerCount=p.followerCount;
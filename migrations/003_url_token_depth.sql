@@ -0,0 +1,7 @@
+-- urlToken 记录从 ownURLToken 开始的 BFS 深度, 用于 maxDepth
+ALTER TABLE `urlToken`
+  ADD COLUMN `depth` int NOT NULL DEFAULT 0;
+
+-- 已有的 urlToken 无法从表中推算深度, 添加字段后都是 0:
+-- 设置 maxDepth 时它们都会被当作第 0 层继续展开, 新发现的 urlToken 深度为 1.
+-- 需要准确的深度时, 在运行前清空 urlToken 和 urlTokenProgress, 重新从 ownURLToken 开始采集.
@@ -0,0 +1,20 @@
+-- 按优先级展开 urlToken (jobs[].priority) 时使用的字段
+ALTER TABLE `urlToken`
+  ADD COLUMN `score` double NOT NULL DEFAULT 0,
+  ADD COLUMN `expanded` tinyint(1) NOT NULL DEFAULT 0,
+  -- GetFrontier: WHERE expanded=0 ORDER BY score DESC
+  ADD KEY `idx_frontier` (`expanded`, `score`);
+
+-- 按优先级展开时的进度, 和按插入顺序展开的 urlTokenProgress 分开保存
+CREATE TABLE IF NOT EXISTS `urlTokenPriorityProgress` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `urlTokenID` bigint unsigned NOT NULL,
+  `nextFolloweeURL` varchar(512) NOT NULL DEFAULT '',
+  `nextFollowerURL` varchar(512) NOT NULL DEFAULT '',
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 已有的 urlToken 分数都是 0, expanded 都是 0:
+-- 按插入顺序展开时会跳过 expanded 的 urlToken, 并在展开完成后设置 expanded,
+-- 所以已经按插入顺序展开过的 urlToken 在第一次按优先级展开时可能会再展开一次.
+-- 分数只在发现 urlToken 时计算, 需要按分数排序已有的 urlToken 时, 清空 urlToken 和进度表后重新采集.
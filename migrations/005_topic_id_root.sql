@@ -0,0 +1,13 @@
+-- 按根话题采集子话题 (jobs[].rootTopicIDs) 时记录每个话题可以从哪些根话题到达
+CREATE TABLE IF NOT EXISTS `topicIDRoot` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `topicID` varchar(32) NOT NULL,
+  `rootTopicID` varchar(32) NOT NULL,
+  `depth` int NOT NULL DEFAULT 0,
+  `expanded` tinyint(1) NOT NULL DEFAULT 0,
+  PRIMARY KEY (`id`),
+  -- InsertTopicIDRoots 依赖这个唯一键跳过已存在的话题 (Error 1062)
+  UNIQUE KEY `uk_topic_root` (`topicID`, `rootTopicID`),
+  -- GetTopicIDRootFrontier: WHERE expanded=0 AND rootTopicID IN (...) ORDER BY depth
+  KEY `idx_frontier` (`expanded`, `rootTopicID`, `depth`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,156 @@
+-- 问题, 回答, 文章, 专栏, 评论, 热榜和它们的进度表 (模式 7 - 14).
+-- 唯一键是 INSERT ... ON DUPLICATE KEY UPDATE 和跳过重复数据 (Error 1062) 的依据, 不能省略.
+
+CREATE TABLE IF NOT EXISTS `question` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `questionID` varchar(32) NOT NULL,
+  `title` varchar(512) NOT NULL DEFAULT '',
+  `answerCount` bigint unsigned NOT NULL DEFAULT 0,
+  `followerCount` bigint unsigned NOT NULL DEFAULT 0,
+  -- 从回答中得到的问题没有创建时间
+  `createdTime` datetime NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_questionID` (`questionID`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `answer` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `answerID` varchar(32) NOT NULL,
+  `questionID` varchar(32) NOT NULL,
+  `authorURLToken` varchar(128) NOT NULL DEFAULT '',
+  -- 采集问题的回答时为 0
+  `urlTokenID` bigint unsigned NOT NULL DEFAULT 0,
+  `voteupCount` bigint unsigned NOT NULL DEFAULT 0,
+  `commentCount` bigint unsigned NOT NULL DEFAULT 0,
+  `createdTime` datetime NOT NULL,
+  `updatedTime` datetime NOT NULL,
+  `content` mediumtext NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_answerID` (`answerID`),
+  KEY `idx_questionID` (`questionID`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `answerProgress` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `questionID` bigint unsigned NOT NULL,
+  `nextAnswerURL` varchar(1024) NOT NULL DEFAULT '',
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `userAnswerProgress` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `urlTokenID` bigint unsigned NOT NULL,
+  `nextAnswerURL` varchar(1024) NOT NULL DEFAULT '',
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `article` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `articleID` varchar(32) NOT NULL,
+  `title` varchar(512) NOT NULL DEFAULT '',
+  `columnID` varchar(64) NOT NULL DEFAULT '',
+  `authorURLToken` varchar(128) NOT NULL DEFAULT '',
+  -- 按专栏采集时为 0
+  `urlTokenID` bigint unsigned NOT NULL DEFAULT 0,
+  `voteupCount` bigint unsigned NOT NULL DEFAULT 0,
+  `commentCount` bigint unsigned NOT NULL DEFAULT 0,
+  `createdTime` datetime NOT NULL,
+  `updatedTime` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_articleID` (`articleID`),
+  KEY `idx_columnID` (`columnID`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- column 是 MySQL 的关键字, 查询中需要使用反引号
+CREATE TABLE IF NOT EXISTS `column` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `columnID` varchar(64) NOT NULL,
+  `title` varchar(256) NOT NULL DEFAULT '',
+  `intro` varchar(1024) NOT NULL DEFAULT '',
+  `authorURLToken` varchar(128) NOT NULL DEFAULT '',
+  `articlesCount` bigint unsigned NOT NULL DEFAULT 0,
+  `followers` bigint unsigned NOT NULL DEFAULT 0,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_columnID` (`columnID`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `userArticleProgress` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `urlTokenID` bigint unsigned NOT NULL,
+  `nextArticleURL` varchar(1024) NOT NULL DEFAULT '',
+  `nextColumnURL` varchar(1024) NOT NULL DEFAULT '',
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `columnArticleProgress` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `columnID` bigint unsigned NOT NULL,
+  `nextArticleURL` varchar(1024) NOT NULL DEFAULT '',
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 同一个问题出现在话题的多个列表中时只记录第一次出现的列表
+CREATE TABLE IF NOT EXISTS `topicQuestion` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `topicID` varchar(32) NOT NULL,
+  `questionID` varchar(32) NOT NULL,
+  `feed` varchar(32) NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_topic_question` (`topicID`, `questionID`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `topicQuestionProgress` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `topicID` bigint unsigned NOT NULL,
+  `feed` varchar(32) NOT NULL DEFAULT '',
+  `nextQuestionURL` varchar(1024) NOT NULL DEFAULT '',
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `comment` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `commentID` varchar(32) NOT NULL,
+  -- answer 或 article
+  `resourceType` varchar(16) NOT NULL,
+  `resourceID` varchar(32) NOT NULL,
+  -- 根评论为空
+  `rootCommentID` varchar(32) NOT NULL DEFAULT '',
+  `authorURLToken` varchar(128) NOT NULL DEFAULT '',
+  `replyToURLToken` varchar(128) NOT NULL DEFAULT '',
+  `content` text NOT NULL,
+  `likeCount` bigint unsigned NOT NULL DEFAULT 0,
+  `createdTime` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_commentID` (`commentID`),
+  KEY `idx_resource` (`resourceType`, `resourceID`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 回答和文章的评论分别保存进度
+CREATE TABLE IF NOT EXISTS `commentProgress` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `resourceType` varchar(16) NOT NULL,
+  `resourceID` bigint unsigned NOT NULL,
+  `nextCommentURL` varchar(1024) NOT NULL DEFAULT '',
+  PRIMARY KEY (`id`),
+  KEY `idx_resourceType` (`resourceType`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 每次快照插入新的行, 不需要唯一键
+CREATE TABLE IF NOT EXISTS `hotList` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `snapshotTime` datetime NOT NULL,
+  `rank` int NOT NULL,
+  `questionID` varchar(32) NOT NULL,
+  `title` varchar(512) NOT NULL DEFAULT '',
+  `heat` bigint unsigned NOT NULL DEFAULT 0,
+  PRIMARY KEY (`id`),
+  KEY `idx_snapshotTime` (`snapshotTime`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `searchProgress` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `keyword` varchar(128) NOT NULL DEFAULT '',
+  `searchType` varchar(16) NOT NULL DEFAULT '',
+  `nextSearchURL` varchar(1024) NOT NULL DEFAULT '',
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,8 @@
+-- topic 保存通过接口采集的话题信息, 重新采集时通过唯一索引更新原来的数据.
+-- 如果已有重复的 topicID, 需要先删除旧的行再添加唯一索引.
+ALTER TABLE `topic`
+  ADD COLUMN `name` varchar(255) NOT NULL DEFAULT '',
+  ADD COLUMN `introduction` text NOT NULL,
+  ADD COLUMN `avatarURL` varchar(512) NOT NULL DEFAULT '',
+  ADD COLUMN `bestAnswerersCount` int unsigned NOT NULL DEFAULT 0,
+  ADD UNIQUE KEY `uk_topicID` (`topicID`);
@@ -0,0 +1,193 @@
+package modules
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/astaxie/beego/logs"
+)
+
+type PagingAnswer struct {
+	Paging *Paging   `json:"paging"`
+	Data   []*Answer `json:"data"`
+}
+
+type Answer struct {
+	ID           uint64        `json:"id"`
+	Type         string        `json:"type"`
+	Question     *QuestionInfo `json:"question"`
+	Author       *Author       `json:"author"`
+	VoteupCount  uint64        `json:"voteup_count"`
+	CommentCount uint64        `json:"comment_count"`
+	CreatedTime  int64         `json:"created_time"`
+	UpdatedTime  int64         `json:"updated_time"`
+	Content      string        `json:"content"`
+}
+
+type QuestionInfo struct {
+	ID    uint64 `json:"id"`
+	Type  string `json:"type"`
+	Title string `json:"title"`
+}
+
+// Author 是回答, 文章和评论的作者, 匿名用户的 URLToken 为空
+type Author struct {
+	ID       string `json:"id"`
+	URLToken string `json:"url_token"`
+	Name     string `json:"name"`
+}
+
+func (a *Answer) toTable() *AnswerTable {
+	at := &AnswerTable{
+		AnswerID:     strconv.FormatUint(a.ID, 10),
+		VoteupCount:  a.VoteupCount,
+		CommentCount: a.CommentCount,
+		CreatedTime:  time.Unix(a.CreatedTime, 0),
+		UpdatedTime:  time.Unix(a.UpdatedTime, 0),
+		Content:      a.Content,
+	}
+	if a.Question != nil {
+		at.QuestionID = strconv.FormatUint(a.Question.ID, 10)
+	}
+	if a.Author != nil {
+		at.AuthorURLToken = a.Author.URLToken
+	}
+	return at
+}
+
+// parseAnswers 解析一页回答, 同时返回回答所属的问题
+func parseAnswers(data []byte) (*PagingAnswer, []*AnswerTable, []*Question, error) {
+	pa := &PagingAnswer{}
+	if err := json.Unmarshal(data, pa); err != nil {
+		return nil, nil, nil, err
+	}
+
+	var answers []*AnswerTable
+	var questions []*Question
+	for _, answer := range pa.Data {
+		answers = append(answers, answer.toTable())
+		if answer.Question != nil {
+			questions = append(questions, &Question{
+				QuestionID: strconv.FormatUint(answer.Question.ID, 10),
+				Title:      answer.Question.Title,
+			})
+		}
+	}
+	return pa, answers, questions, nil
+}
+
+// answerSaver 返回保存一页回答和回答所属问题的 pageHandler, urlTokenID 是回答作者的 id
+func (zh *ZhiHu) answerSaver(urlTokenID uint64) pageHandler {
+	return func(ctx context.Context, data []byte) (*Paging, int, error) {
+		return zh.saveAnswers(ctx, data, urlTokenID)
+	}
+}
+
+func (zh *ZhiHu) saveAnswers(ctx context.Context, data []byte, urlTokenID uint64) (*Paging, int, error) {
+	pa, answers, questions, err := parseAnswers(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, answer := range answers {
+		answer.URLTokenID = urlTokenID
+	}
+
+	if err := zh.dataSource.InsertQuestions(ctx, questions); err != nil {
+		return nil, 0, err
+	}
+	if err := zh.dataSource.InsertAnswers(ctx, answers); err != nil {
+		return nil, 0, err
+	}
+	return pa.Paging, len(answers), nil
+}
+
+// CollectQuestionAnswers 依次采集 question 表中每个问题的回答, 每个问题最多 MaxPages 页
+func (zh *ZhiHu) CollectQuestionAnswers(ctx context.Context, job *CollectJob) {
+	ds := zh.dataSource
+
+	var questions []*Question
+	for _, id := range job.config.QuestionIDs {
+		questions = append(questions, &Question{QuestionID: id})
+	}
+	if err := ds.InsertQuestions(ctx, questions); err != nil {
+		logs.Error("error when insert questions: %s", err)
+		return
+	}
+
+	questionItem := func(question *Question) *walkItem {
+		return &walkItem{
+			id:    question.ID,
+			name:  question.QuestionID,
+			lists: []walkList{{zh.api.QuestionAnswersURL(question.QuestionID), zh.answerSaver(0)}},
+		}
+	}
+	rw := &resumableWalk{
+		name: "question answers",
+		loadProgress: func(ctx context.Context) (uint64, int, string, error) {
+			ap, err := ds.GetAnswerProgress(ctx)
+			return ap.QuestionID, 0, ap.NextAnswerURL, err
+		},
+		saveProgress: func(ctx context.Context, id uint64, list int, nextURL string) error {
+			return ds.InsertAnswerProgress(ctx, &AnswerProgress{QuestionID: id, NextAnswerURL: nextURL})
+		},
+		offset: ds.GetQuestionOffset,
+		get: func(ctx context.Context, offset uint64) (*walkItem, error) {
+			question, err := ds.GetQuestion(ctx, offset)
+			if err != nil {
+				return nil, err
+			}
+			return questionItem(question), nil
+		},
+	}
+
+	// 配置了问题时只采集这些问题
+	if len(job.config.QuestionIDs) != 0 {
+		var items []*walkItem
+		seen := make(map[string]bool)
+		for _, id := range job.config.QuestionIDs {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+
+			question, err := ds.GetQuestionByQuestionID(ctx, id)
+			if err != nil {
+				logs.Error("error when get question: %s, err: %s", id, err)
+				return
+			}
+			items = append(items, questionItem(question))
+		}
+		rw.only(items)
+	}
+	zh.walk(ctx, job, rw)
+}
+
+// CollectUserAnswers 依次采集 urlToken 表中每个用户的回答, 每个用户最多 MaxPages 页
+func (zh *ZhiHu) CollectUserAnswers(ctx context.Context, job *CollectJob) {
+	ds := zh.dataSource
+
+	zh.walk(ctx, job, &resumableWalk{
+		name: "user answers",
+		loadProgress: func(ctx context.Context) (uint64, int, string, error) {
+			uap, err := ds.GetUserAnswerProgress(ctx)
+			return uap.URLTokenID, 0, uap.NextAnswerURL, err
+		},
+		saveProgress: func(ctx context.Context, id uint64, list int, nextURL string) error {
+			return ds.InsertUserAnswerProgress(ctx, &UserAnswerProgress{URLTokenID: id, NextAnswerURL: nextURL})
+		},
+		byID: true,
+		get: func(ctx context.Context, afterID uint64) (*walkItem, error) {
+			ut, err := ds.GetURLToken(ctx, afterID)
+			if err != nil {
+				return nil, err
+			}
+			return &walkItem{
+				id:    ut.ID,
+				name:  ut.URLToken,
+				lists: []walkList{{zh.api.MemberAnswersURL(ut.URLToken), zh.answerSaver(ut.ID)}},
+			}, nil
+		},
+	})
+}
@@ -0,0 +1,213 @@
+package modules
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	defaultBaseURL      = "https://www.zhihu.com"
+	defaultCookieDomain = ".zhihu.com"
+)
+
+const (
+	defaultFolloweeAPI    = `/api/v4/members/%s/followees?offset=0&limit=20`
+	defaultFollowerAPI    = `/api/v4/members/%s/followers?offset=0&limit=20`
+	defaultUserSumInfoAPI = `/api/v4/members/%s`
+	defaultTopicIDAPI     = `/api/v3/topics/%s/children`
+	defaultPeopleAPI      = `/people/%s/activities`
+	defaultTopicWebPage   = `/topic/%s/hot`
+	defaultTopicAPI       = `/api/v4/topics/%s`
+
+	defaultQuestionAnswersAPI = `/api/v4/questions/%s/answers?offset=0&limit=20&sort_by=default`
+	defaultMemberAnswersAPI   = `/api/v4/members/%s/answers?offset=0&limit=20&sort_by=created`
+	defaultMemberArticlesAPI  = `/api/v4/members/%s/articles?offset=0&limit=20&sort_by=created`
+	defaultMemberColumnsAPI   = `/api/v4/members/%s/column-contributions?offset=0&limit=20`
+	defaultColumnArticlesAPI  = `/api/v4/columns/%s/items?offset=0&limit=20`
+	defaultTopicTopAPI        = `/api/v4/topics/%s/feeds/top_activity?offset=0&limit=10`
+	defaultTopicEssenceAPI    = `/api/v4/topics/%s/feeds/essence?offset=0&limit=10`
+	defaultTopicTimelineAPI   = `/api/v4/topics/%s/feeds/timeline_question?offset=0&limit=10`
+	defaultAnswerCommentsAPI  = `/api/v4/answers/%s/root_comments?order=normal&offset=0&limit=20&status=open`
+	defaultArticleCommentsAPI = `/api/v4/articles/%s/root_comments?order=normal&offset=0&limit=20&status=open`
+	defaultChildCommentsAPI   = `/api/v4/comments/%s/child_comments?offset=0&limit=20`
+	defaultHotListAPI         = `/api/v3/feed/topstory/hot-lists/total?limit=50&desktop=true`
+	defaultSearchPeopleAPI    = `/api/v4/search_v3?t=people&q=%s&correction=1&offset=0&limit=20`
+	defaultSearchTopicAPI     = `/api/v4/search_v3?t=topic&q=%s&correction=1&offset=0&limit=20`
+	defaultSearchQuestionAPI  = `/api/v4/search_v3?t=general&q=%s&correction=1&offset=0&limit=20`
+)
+
+const (
+	defaultFollowInclude       = `data[*].answer_count,articles_count,gender,follower_count,is_followed,is_following,badge[?(type=best_answerer)].topics`
+	defaultUserSumInfoInclude  = `allow_message,is_followed,is_following,is_org,is_blocking,employments,answer_count,follower_count,articles_count,gender,badge[?(type=best_answerer)].topics`
+	defaultTopicInclude        = `introduction,followers_count,questions_count,best_answerers_count`
+	defaultAnswerInclude       = `data[*].content,voteup_count,comment_count,created_time,updated_time,question`
+	defaultMemberAnswerInclude = `data[*].voteup_count,comment_count,created_time,updated_time,question`
+	defaultArticleInclude      = `data[*].voteup_count,comment_count,created,updated,column`
+	defaultColumnInclude       = `data[*].column.intro,followers,articles_count`
+	defaultTopicFeedInclude    = `data[*].target.answer_count,follower_count,created,question.answer_count,follower_count,created`
+)
+
+// setDefault 为未配置的字段填充知乎的默认值
+func (ac *APIConfig) setDefault() {
+	setDefaultString(&ac.BaseURL, defaultBaseURL)
+	setDefaultString(&ac.CookieDomain, defaultCookieDomain)
+	ac.BaseURL = strings.TrimSuffix(ac.BaseURL, "/")
+
+	setDefaultString(&ac.FolloweeAPI, defaultFolloweeAPI)
+	setDefaultString(&ac.FollowerAPI, defaultFollowerAPI)
+	setDefaultString(&ac.UserSumInfoAPI, defaultUserSumInfoAPI)
+	setDefaultString(&ac.TopicIDAPI, defaultTopicIDAPI)
+	setDefaultString(&ac.PeopleAPI, defaultPeopleAPI)
+	setDefaultString(&ac.TopicWebPage, defaultTopicWebPage)
+	setDefaultString(&ac.TopicAPI, defaultTopicAPI)
+	setDefaultString(&ac.QuestionAnswersAPI, defaultQuestionAnswersAPI)
+	setDefaultString(&ac.MemberAnswersAPI, defaultMemberAnswersAPI)
+	setDefaultString(&ac.MemberArticlesAPI, defaultMemberArticlesAPI)
+	setDefaultString(&ac.MemberColumnsAPI, defaultMemberColumnsAPI)
+	setDefaultString(&ac.ColumnArticlesAPI, defaultColumnArticlesAPI)
+	setDefaultString(&ac.TopicTopAPI, defaultTopicTopAPI)
+	setDefaultString(&ac.TopicEssenceAPI, defaultTopicEssenceAPI)
+	setDefaultString(&ac.TopicTimelineAPI, defaultTopicTimelineAPI)
+	setDefaultString(&ac.AnswerCommentsAPI, defaultAnswerCommentsAPI)
+	setDefaultString(&ac.ArticleCommentsAPI, defaultArticleCommentsAPI)
+	setDefaultString(&ac.ChildCommentsAPI, defaultChildCommentsAPI)
+	setDefaultString(&ac.HotListAPI, defaultHotListAPI)
+	setDefaultString(&ac.SearchPeopleAPI, defaultSearchPeopleAPI)
+	setDefaultString(&ac.SearchTopicAPI, defaultSearchTopicAPI)
+	setDefaultString(&ac.SearchQuestionAPI, defaultSearchQuestionAPI)
+
+	setDefaultString(&ac.FollowInclude, defaultFollowInclude)
+	setDefaultString(&ac.UserSumInfoInclude, defaultUserSumInfoInclude)
+	setDefaultString(&ac.TopicInclude, defaultTopicInclude)
+	setDefaultString(&ac.AnswerInclude, defaultAnswerInclude)
+	setDefaultString(&ac.MemberAnswerInclude, defaultMemberAnswerInclude)
+	setDefaultString(&ac.ArticleInclude, defaultArticleInclude)
+	setDefaultString(&ac.ColumnInclude, defaultColumnInclude)
+	setDefaultString(&ac.TopicFeedInclude, defaultTopicFeedInclude)
+}
+
+func (ac *APIConfig) FolloweeURL(urlToken string) string {
+	return ac.build(ac.FolloweeAPI, urlToken, ac.FollowInclude)
+}
+
+func (ac *APIConfig) FollowerURL(urlToken string) string {
+	return ac.build(ac.FollowerAPI, urlToken, ac.FollowInclude)
+}
+
+func (ac *APIConfig) UserSumInfoURL(urlToken string) string {
+	return ac.build(ac.UserSumInfoAPI, urlToken, ac.UserSumInfoInclude)
+}
+
+func (ac *APIConfig) TopicIDURL(topicID string) string {
+	return ac.build(ac.TopicIDAPI, topicID, "")
+}
+
+func (ac *APIConfig) PeopleURL(urlToken string) string {
+	return ac.build(ac.PeopleAPI, urlToken, "")
+}
+
+func (ac *APIConfig) TopicWebPageURL(topicID string) string {
+	return ac.build(ac.TopicWebPage, topicID, "")
+}
+
+func (ac *APIConfig) TopicURL(topicID string) string {
+	return ac.build(ac.TopicAPI, topicID, ac.TopicInclude)
+}
+
+func (ac *APIConfig) QuestionAnswersURL(questionID string) string {
+	return ac.build(ac.QuestionAnswersAPI, questionID, ac.AnswerInclude)
+}
+
+func (ac *APIConfig) MemberAnswersURL(urlToken string) string {
+	return ac.build(ac.MemberAnswersAPI, urlToken, ac.MemberAnswerInclude)
+}
+
+func (ac *APIConfig) MemberArticlesURL(urlToken string) string {
+	return ac.build(ac.MemberArticlesAPI, urlToken, ac.ArticleInclude)
+}
+
+func (ac *APIConfig) MemberColumnsURL(urlToken string) string {
+	return ac.build(ac.MemberColumnsAPI, urlToken, ac.ColumnInclude)
+}
+
+func (ac *APIConfig) ColumnArticlesURL(columnID string) string {
+	return ac.build(ac.ColumnArticlesAPI, columnID, ac.ArticleInclude)
+}
+
+// TopicFeedURL 返回话题的精华, 讨论或等待回答的问题列表, feed 不存在时返回空字符串
+func (ac *APIConfig) TopicFeedURL(topicID, feed string) string {
+	var template string
+	switch feed {
+	case topicFeedTop:
+		template = ac.TopicTopAPI
+	case topicFeedEssence:
+		template = ac.TopicEssenceAPI
+	case topicFeedTimeline:
+		template = ac.TopicTimelineAPI
+	default:
+		return ""
+	}
+	return ac.build(template, topicID, ac.TopicFeedInclude)
+}
+
+// RootCommentsURL 返回回答或文章的根评论列表, resourceType 不存在时返回空字符串
+func (ac *APIConfig) RootCommentsURL(resourceType, resourceID string) string {
+	switch resourceType {
+	case commentResourceAnswer:
+		return ac.build(ac.AnswerCommentsAPI, resourceID, "")
+	case commentResourceArticle:
+		return ac.build(ac.ArticleCommentsAPI, resourceID, "")
+	}
+	return ""
+}
+
+func (ac *APIConfig) ChildCommentsURL(commentID string) string {
+	return ac.build(ac.ChildCommentsAPI, commentID, "")
+}
+
+// HotListURL 返回热榜的地址, 热榜接口没有占位符
+func (ac *APIConfig) HotListURL() string {
+	if strings.HasPrefix(ac.HotListAPI, "/") {
+		return ac.BaseURL + ac.HotListAPI
+	}
+	return ac.HotListAPI
+}
+
+// SearchURL 返回关键词的搜索地址, searchType 不存在时返回空字符串
+func (ac *APIConfig) SearchURL(searchType, keyword string) string {
+	var template string
+	switch searchType {
+	case searchPeople:
+		template = ac.SearchPeopleAPI
+	case searchTopic:
+		template = ac.SearchTopicAPI
+	case searchQuestion:
+		template = ac.SearchQuestionAPI
+	default:
+		return ""
+	}
+	return ac.build(template, url.QueryEscape(keyword), "")
+}
+
+func (ac *APIConfig) build(template, id, include string) string {
+	rawURL := fmt.Sprintf(template, id)
+	if strings.HasPrefix(rawURL, "/") {
+		rawURL = ac.BaseURL + rawURL
+	}
+	if include == "" {
+		return rawURL
+	}
+
+	sep := "?"
+	if strings.Contains(rawURL, "?") {
+		sep = "&"
+	}
+	return rawURL + sep + "include=" + include
+}
+
+func setDefaultString(field *string, value string) {
+	if *field == "" {
+		*field = value
+	}
+}
@@ -0,0 +1,255 @@
+package modules
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/astaxie/beego/logs"
+)
+
+type PagingArticle struct {
+	Paging *Paging    `json:"paging"`
+	Data   []*Article `json:"data"`
+}
+
+type Article struct {
+	ID           uint64      `json:"id"`
+	Type         string      `json:"type"`
+	Title        string      `json:"title"`
+	Author       *Author     `json:"author"`
+	Column       *ColumnInfo `json:"column"`
+	VoteupCount  uint64      `json:"voteup_count"`
+	CommentCount uint64      `json:"comment_count"`
+	Created      int64       `json:"created"`
+	Updated      int64       `json:"updated"`
+}
+
+type ColumnInfo struct {
+	ID            string  `json:"id"`
+	Title         string  `json:"title"`
+	Intro         string  `json:"intro"`
+	Author        *Author `json:"author"`
+	ArticlesCount uint64  `json:"articles_count"`
+	Followers     uint64  `json:"followers"`
+}
+
+type PagingColumnContribution struct {
+	Paging *Paging               `json:"paging"`
+	Data   []*ColumnContribution `json:"data"`
+}
+
+type ColumnContribution struct {
+	Column *ColumnInfo `json:"column"`
+	// own 表示用户是专栏的作者
+	ContributeType string `json:"contribute_type"`
+}
+
+func (a *Article) toTable() *ArticleTable {
+	at := &ArticleTable{
+		ArticleID:    strconv.FormatUint(a.ID, 10),
+		Title:        a.Title,
+		VoteupCount:  a.VoteupCount,
+		CommentCount: a.CommentCount,
+		CreatedTime:  time.Unix(a.Created, 0),
+		UpdatedTime:  time.Unix(a.Updated, 0),
+	}
+	if a.Column != nil {
+		at.ColumnID = a.Column.ID
+	}
+	if a.Author != nil {
+		at.AuthorURLToken = a.Author.URLToken
+	}
+	return at
+}
+
+func (ci *ColumnInfo) toTable() *ColumnTable {
+	ct := &ColumnTable{
+		ColumnID:      ci.ID,
+		Title:         ci.Title,
+		Intro:         ci.Intro,
+		ArticlesCount: ci.ArticlesCount,
+		Followers:     ci.Followers,
+	}
+	if ci.Author != nil {
+		ct.AuthorURLToken = ci.Author.URLToken
+	}
+	return ct
+}
+
+// parseArticles 解析一页文章, 同时返回文章所属的专栏; 专栏的列表中可能有其他类型的数据
+func parseArticles(data []byte) (*PagingArticle, []*ArticleTable, []*ColumnTable, error) {
+	pa := &PagingArticle{}
+	if err := json.Unmarshal(data, pa); err != nil {
+		return nil, nil, nil, err
+	}
+
+	var articles []*ArticleTable
+	var columns []*ColumnTable
+	for _, article := range pa.Data {
+		if article.Type != "" && article.Type != "article" {
+			continue
+		}
+		articles = append(articles, article.toTable())
+		if article.Column != nil && article.Column.ID != "" {
+			columns = append(columns, &ColumnTable{
+				ColumnID: article.Column.ID,
+				Title:    article.Column.Title,
+			})
+		}
+	}
+	return pa, articles, columns, nil
+}
+
+// parseColumns 解析一页用户参与的专栏, 只返回用户是作者的专栏
+func parseColumns(data []byte) (*PagingColumnContribution, []*ColumnTable, error) {
+	pc := &PagingColumnContribution{}
+	if err := json.Unmarshal(data, pc); err != nil {
+		return nil, nil, err
+	}
+
+	var columns []*ColumnTable
+	for _, contribution := range pc.Data {
+		if contribution.Column == nil || (contribution.ContributeType != "" && contribution.ContributeType != "own") {
+			continue
+		}
+		columns = append(columns, contribution.Column.toTable())
+	}
+	return pc, columns, nil
+}
+
+// articleSaver 返回保存一页文章和文章所属专栏的 pageHandler, urlTokenID 是文章作者的 id
+func (zh *ZhiHu) articleSaver(urlTokenID uint64) pageHandler {
+	return func(ctx context.Context, data []byte) (*Paging, int, error) {
+		pa, articles, columns, err := parseArticles(data)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, article := range articles {
+			article.URLTokenID = urlTokenID
+		}
+
+		if err := zh.dataSource.InsertColumns(ctx, columns); err != nil {
+			return nil, 0, err
+		}
+		if err := zh.dataSource.InsertArticles(ctx, articles); err != nil {
+			return nil, 0, err
+		}
+		// 返回整页的数量, 过滤掉的数据不能当作最后一页
+		return pa.Paging, len(pa.Data), nil
+	}
+}
+
+func (zh *ZhiHu) saveColumns(ctx context.Context, data []byte) (*Paging, int, error) {
+	pc, columns, err := parseColumns(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := zh.dataSource.InsertColumns(ctx, columns); err != nil {
+		return nil, 0, err
+	}
+	return pc.Paging, len(pc.Data), nil
+}
+
+// CollectUserArticles 依次采集 urlToken 表中每个用户的文章和专栏, 每个用户最多 MaxPages 页
+func (zh *ZhiHu) CollectUserArticles(ctx context.Context, job *CollectJob) {
+	ds := zh.dataSource
+
+	zh.walk(ctx, job, &resumableWalk{
+		name: "user articles",
+		// 两个地址都为空时从头开始采集用户, 只有 NextArticleURL 为空时表示文章已经采集完
+		loadProgress: func(ctx context.Context) (uint64, int, string, error) {
+			uap, err := ds.GetUserArticleProgress(ctx)
+			if uap.NextArticleURL == "" && uap.NextColumnURL != "" {
+				return uap.URLTokenID, 1, uap.NextColumnURL, err
+			}
+			return uap.URLTokenID, 0, uap.NextArticleURL, err
+		},
+		saveProgress: func(ctx context.Context, id uint64, list int, nextURL string) error {
+			uap := &UserArticleProgress{URLTokenID: id}
+			if list == 0 {
+				uap.NextArticleURL = nextURL
+			} else {
+				uap.NextColumnURL = nextURL
+			}
+			return ds.InsertUserArticleProgress(ctx, uap)
+		},
+		byID: true,
+		get: func(ctx context.Context, afterID uint64) (*walkItem, error) {
+			ut, err := ds.GetURLToken(ctx, afterID)
+			if err != nil {
+				return nil, err
+			}
+			return &walkItem{
+				id:   ut.ID,
+				name: ut.URLToken,
+				lists: []walkList{
+					{zh.api.MemberArticlesURL(ut.URLToken), zh.articleSaver(ut.ID)},
+					{zh.api.MemberColumnsURL(ut.URLToken), zh.saveColumns},
+				},
+			}, nil
+		},
+	})
+}
+
+// CollectColumnArticles 依次采集 column 表中每个专栏的文章, 每个专栏最多 MaxPages 页
+func (zh *ZhiHu) CollectColumnArticles(ctx context.Context, job *CollectJob) {
+	ds := zh.dataSource
+
+	var columns []*ColumnTable
+	for _, id := range job.config.ColumnIDs {
+		columns = append(columns, &ColumnTable{ColumnID: id})
+	}
+	if err := ds.InsertColumns(ctx, columns); err != nil {
+		logs.Error("error when insert columns: %s", err)
+		return
+	}
+
+	columnItem := func(column *ColumnTable) *walkItem {
+		return &walkItem{
+			id:    column.ID,
+			name:  column.ColumnID,
+			lists: []walkList{{zh.api.ColumnArticlesURL(column.ColumnID), zh.articleSaver(0)}},
+		}
+	}
+	rw := &resumableWalk{
+		name: "column articles",
+		loadProgress: func(ctx context.Context) (uint64, int, string, error) {
+			cp, err := ds.GetColumnArticleProgress(ctx)
+			return cp.ColumnID, 0, cp.NextArticleURL, err
+		},
+		saveProgress: func(ctx context.Context, id uint64, list int, nextURL string) error {
+			return ds.InsertColumnArticleProgress(ctx, &ColumnArticleProgress{ColumnID: id, NextArticleURL: nextURL})
+		},
+		offset: ds.GetColumnOffset,
+		get: func(ctx context.Context, offset uint64) (*walkItem, error) {
+			column, err := ds.GetColumn(ctx, offset)
+			if err != nil {
+				return nil, err
+			}
+			return columnItem(column), nil
+		},
+	}
+
+	// 配置了专栏时只采集这些专栏
+	if len(job.config.ColumnIDs) != 0 {
+		var items []*walkItem
+		seen := make(map[string]bool)
+		for _, id := range job.config.ColumnIDs {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+
+			column, err := ds.GetColumnByColumnID(ctx, id)
+			if err != nil {
+				logs.Error("error when get column: %s, err: %s", id, err)
+				return
+			}
+			items = append(items, columnItem(column))
+		}
+		rw.only(items)
+	}
+	zh.walk(ctx, job, rw)
+}
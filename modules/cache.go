@@ -0,0 +1,160 @@
+package modules
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/astaxie/beego/logs"
+)
+
+const cacheFileSuffix = ".resp"
+
+// newCacheTransport 创建以 url 为 key 的磁盘缓存, 只缓存 GET 请求中 checkResponse 认为正常的 200 响应.
+// maxBodySize 以上的响应不会被缓存.
+func newCacheTransport(config *CacheConfig, maxBodySize int64, next http.RoundTripper) (*cacheTransport, error) {
+	if config == nil || config.Dir == "" {
+		return nil, fmt.Errorf("invalid cache config")
+	}
+
+	var ttl time.Duration
+	if config.TTL != "" {
+		dur, err := time.ParseDuration(config.TTL)
+		if err != nil {
+			return nil, err
+		}
+		ttl = dur
+	}
+
+	if err := os.MkdirAll(config.Dir, 0755); err != nil {
+		return nil, err
+	}
+
+	ct := &cacheTransport{
+		dir:         config.Dir,
+		ttl:         ttl,
+		offline:     config.Offline,
+		maxBodySize: maxBodySize,
+		next:        next,
+	}
+	return ct, nil
+}
+
+type cacheTransport struct {
+	dir         string
+	ttl         time.Duration
+	offline     bool
+	maxBodySize int64
+
+	next http.RoundTripper
+}
+
+func (ct *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rawURL := req.URL.String()
+	if req.Method != http.MethodGet {
+		if ct.offline {
+			return nil, fmt.Errorf("offline mode only supports GET, method: %s, url: %s", req.Method, rawURL)
+		}
+		return ct.next.RoundTrip(req)
+	}
+
+	path := ct.path(rawURL)
+	resp, err := ct.load(path, req)
+	if err == nil {
+		logs.Debug("cache hit: %s", rawURL)
+		return resp, nil
+	}
+	if ct.offline {
+		return nil, fmt.Errorf("cache miss in offline mode, url: %s, err: %s", rawURL, err)
+	}
+
+	resp, err = ct.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+
+	// 先按限制读取 body, 避免 DumpResponse 读取过大的响应
+	ok, err := bufferBody(resp, ct.maxBodySize)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		logs.Warn("response body too large to cache, url: %s, limit: %d", rawURL, ct.maxBodySize)
+		return resp, nil
+	}
+
+	// 验证页面和错误信息也可能是 200, 缓存后每次都会返回同样的错误
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err := checkResponse(resp, body); err != nil {
+		logs.Debug("response not cached: %s", err)
+		return resp, nil
+	}
+
+	// DumpResponse 会读取 body 并重新赋值给 resp.Body
+	data, err := httputil.DumpResponse(resp, true)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if err := ct.save(path, data); err != nil {
+		logs.Warn("error when save cache, url: %s, err: %s", rawURL, err)
+	}
+	return resp, nil
+}
+
+func (ct *cacheTransport) path(rawURL string) string {
+	sum := sha1.Sum([]byte(rawURL))
+	return filepath.Join(ct.dir, hex.EncodeToString(sum[:])+cacheFileSuffix)
+}
+
+func (ct *cacheTransport) load(path string, req *http.Request) (*http.Response, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	// 离线模式下过期的缓存也可以使用
+	if !ct.offline && ct.ttl > 0 && time.Since(info.ModTime()) > ct.ttl {
+		return nil, fmt.Errorf("cache expired: %s", path)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), req)
+}
+
+// save 先写临时文件再重命名, 避免中断时留下不完整的缓存.
+// 临时文件的名字是随机的, 多个任务同时保存同一个 url 时不会互相覆盖.
+func (ct *cacheTransport) save(path string, data []byte) error {
+	tmp, err := ioutil.TempFile(ct.dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
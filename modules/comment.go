@@ -0,0 +1,173 @@
+package modules
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/astaxie/beego/logs"
+)
+
+// 有评论的资源, 按顺序采集
+const (
+	commentResourceAnswer  = "answer"
+	commentResourceArticle = "article"
+)
+
+type PagingComment struct {
+	Paging *Paging    `json:"paging"`
+	Data   []*Comment `json:"data"`
+}
+
+type Comment struct {
+	ID                uint64         `json:"id"`
+	Content           string         `json:"content"`
+	VoteCount         uint64         `json:"vote_count"`
+	CreatedTime       int64          `json:"created_time"`
+	Author            *CommentAuthor `json:"author"`
+	ReplyToAuthor     *CommentAuthor `json:"reply_to_author"`
+	ChildCommentCount uint64         `json:"child_comment_count"`
+}
+
+type CommentAuthor struct {
+	Member *Author `json:"member"`
+}
+
+func (ca *CommentAuthor) urlToken() string {
+	if ca == nil || ca.Member == nil {
+		return ""
+	}
+	return ca.Member.URLToken
+}
+
+// parseComments 解析一页根评论或子评论, rootCommentID 为空时是根评论
+func parseComments(data []byte, resourceType, resourceID, rootCommentID string) (*PagingComment, []*CommentTable, error) {
+	pc := &PagingComment{}
+	if err := json.Unmarshal(data, pc); err != nil {
+		return nil, nil, err
+	}
+
+	var comments []*CommentTable
+	for _, comment := range pc.Data {
+		comments = append(comments, &CommentTable{
+			CommentID:       strconv.FormatUint(comment.ID, 10),
+			ResourceType:    resourceType,
+			ResourceID:      resourceID,
+			RootCommentID:   rootCommentID,
+			AuthorURLToken:  comment.Author.urlToken(),
+			ReplyToURLToken: comment.ReplyToAuthor.urlToken(),
+			Content:         comment.Content,
+			LikeCount:       comment.VoteCount,
+			CreatedTime:     time.Unix(comment.CreatedTime, 0),
+		})
+	}
+	return pc, comments, nil
+}
+
+// rootCommentSaver 返回保存一页根评论的 pageHandler, 有子评论的根评论会接着采集全部子评论.
+// 子评论被中断时返回错误, 下次从这一页根评论重新开始.
+func (zh *ZhiHu) rootCommentSaver(job *CollectJob, resourceType, resourceID string) pageHandler {
+	return func(ctx context.Context, data []byte) (*Paging, int, error) {
+		pc, comments, err := parseComments(data, resourceType, resourceID, "")
+		if err != nil {
+			return nil, 0, err
+		}
+		if err := zh.dataSource.InsertComments(ctx, comments); err != nil {
+			return nil, 0, err
+		}
+
+		for i, comment := range pc.Data {
+			if comment.ChildCommentCount == 0 {
+				continue
+			}
+			rootCommentID := comments[i].CommentID
+			_, err := zh.continuePaging(ctx, job, zh.api.ChildCommentsURL(rootCommentID), job.config.MaxPages,
+				zh.childCommentSaver(resourceType, resourceID, rootCommentID))
+			if ctx.Err() != nil {
+				// 子评论已经采集完时 err 为 nil, 也需要返回错误, 否则这一页之后的根评论会被跳过
+				return nil, 0, ctx.Err()
+			} else if err != nil && !errors.Is(err, ErrGone) {
+				logs.Error("error when collect child comments, commentID: %s, err: %s", rootCommentID, err)
+			}
+		}
+		return pc.Paging, len(comments), nil
+	}
+}
+
+func (zh *ZhiHu) childCommentSaver(resourceType, resourceID, rootCommentID string) pageHandler {
+	return func(ctx context.Context, data []byte) (*Paging, int, error) {
+		pc, comments, err := parseComments(data, resourceType, resourceID, rootCommentID)
+		if err != nil {
+			return nil, 0, err
+		}
+		if err := zh.dataSource.InsertComments(ctx, comments); err != nil {
+			return nil, 0, err
+		}
+		return pc.Paging, len(comments), nil
+	}
+}
+
+// CollectComments 依次采集 answer 表和 article 表中每个回答和文章的评论, 两种资源分别保存进度
+func (zh *ZhiHu) CollectComments(ctx context.Context, job *CollectJob) {
+	for _, resourceType := range []string{commentResourceAnswer, commentResourceArticle} {
+		zh.collectResourceComments(ctx, job, resourceType)
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// getCommentResource 返回 resourceType 对应的表中第 offset 个回答或文章的 id
+func (zh *ZhiHu) getCommentResource(ctx context.Context, resourceType string, offset uint64) (uint64, string, error) {
+	if resourceType == commentResourceArticle {
+		at, err := zh.dataSource.GetArticle(ctx, offset)
+		return at.ID, at.ArticleID, err
+	}
+	at, err := zh.dataSource.GetAnswer(ctx, offset)
+	return at.ID, at.AnswerID, err
+}
+
+func (zh *ZhiHu) getCommentResourceOffset(ctx context.Context, resourceType string, id uint64) (uint64, error) {
+	if resourceType == commentResourceArticle {
+		return zh.dataSource.GetArticleOffset(ctx, id)
+	}
+	return zh.dataSource.GetAnswerOffset(ctx, id)
+}
+
+func (zh *ZhiHu) collectResourceComments(ctx context.Context, job *CollectJob, resourceType string) {
+	ds := zh.dataSource
+
+	zh.walk(ctx, job, &resumableWalk{
+		name: resourceType + " comments",
+		loadProgress: func(ctx context.Context) (uint64, int, string, error) {
+			cp, err := ds.GetCommentProgress(ctx, resourceType)
+			return cp.ResourceID, 0, cp.NextCommentURL, err
+		},
+		saveProgress: func(ctx context.Context, id uint64, list int, nextURL string) error {
+			return ds.InsertCommentProgress(ctx, &CommentProgress{
+				ResourceType:   resourceType,
+				ResourceID:     id,
+				NextCommentURL: nextURL,
+			})
+		},
+		offset: func(ctx context.Context, id uint64) (uint64, error) {
+			return zh.getCommentResourceOffset(ctx, resourceType, id)
+		},
+		get: func(ctx context.Context, offset uint64) (*walkItem, error) {
+			id, resourceID, err := zh.getCommentResource(ctx, resourceType, offset)
+			if err != nil {
+				return nil, err
+			}
+			return &walkItem{
+				id:   id,
+				name: resourceID,
+				lists: []walkList{{
+					zh.api.RootCommentsURL(resourceType, resourceID),
+					zh.rootCommentSaver(job, resourceType, resourceID),
+				}},
+			}, nil
+		},
+	})
+}
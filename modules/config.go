@@ -21,6 +21,7 @@ type Config struct {
 	MySQL *MySQLConfig `json:"mysql"`
 	ZhiHu *ZhiHuConfig `json:"zhiHu"`
 	Email *EmailConfig `json:"email"`
+	HTTP  *HTTPConfig  `json:"http"`
 }
 
 type MySQLConfig struct {
@@ -36,9 +37,157 @@ type ZhiHuConfig struct {
 	OwnURLToken   string `json:"ownURLToken"`
 	RootTopicID   string `json:"rootTopicID"`
 	PauseDuration string `json:"pauseDuration"`
+	// CoolDown 被拦截后的初始暂停时间, 连续被拦截时翻倍直到 MaxCoolDown
+	CoolDown    string `json:"coolDown"`
+	MaxCoolDown string `json:"maxCoolDown"`
+	// API 为空时使用知乎的默认地址
+	API *APIConfig `json:"api"`
+	// 流水线模式中等待采集用户信息的 urlToken 数量, 队列满时暂停发现新的 urlToken
+	PipelineQueueSize int `json:"pipelineQueueSize"`
+	// Jobs 为空时只运行 Mode 对应的任务
+	Jobs []*JobConfig `json:"jobs"`
+}
+
+// JobConfig 是一个采集任务, 多个任务在同一个进程中同时运行.
+type JobConfig struct {
+	Name string `json:"name"`
+	Mode int    `json:"mode"`
+	// Schedule 为空时只运行一次, 支持 cron 表达式, "@every 1h" 和 "@continuous"
+	Schedule string `json:"schedule"`
+	// Missed 是错过运行时间后的处理方式: "skip"(默认) 或 "run"
+	Missed string `json:"missed"`
+	// RefreshAge 是 refreshPeople 模式中用户信息的有效期, 默认 30 天
+	RefreshAge string `json:"refreshAge"`
+	// 采集 urlToken 或子话题时最多展开到 MaxDepth 层, 每次运行最多新插入 MaxNodes 个 urlToken, 0 表示不限制
+	MaxDepth int    `json:"maxDepth"`
+	MaxNodes uint64 `json:"maxNodes"`
+	// MaxDuration 是每次运行的最长时间, 超时后保存进度并结束本次运行
+	MaxDuration string `json:"maxDuration"`
+	// Priority 不为空时按分数从高到低展开 urlToken, 否则按插入顺序
+	Priority *PriorityConfig `json:"priority"`
+	// Direction 是展开的方向: "both"(默认), "followee" 或 "follower"
+	Direction string `json:"direction"`
+	// 每个用户最多请求的关注和粉丝的页数, 0 表示不限制
+	MaxFolloweePages int `json:"maxFolloweePages"`
+	MaxFollowerPages int `json:"maxFollowerPages"`
+	// RootTopicIDs 不为空时只采集这些话题的子话题, 并记录每个话题属于哪些根话题
+	RootTopicIDs []string `json:"rootTopicIDs"`
+	// ExcludeTopicIDs 中的话题和只能通过它们到达的子话题不会被采集
+	ExcludeTopicIDs []string `json:"excludeTopicIDs"`
+	// QuestionIDs 会在采集回答前加入 question 表, 不为空时每次运行只采集这些问题的回答, 不保存进度
+	QuestionIDs []string `json:"questionIDs"`
+	// ColumnIDs 会在采集专栏文章前加入 column 表, 不为空时每次运行只采集这些专栏的文章, 不保存进度
+	ColumnIDs []string `json:"columnIDs"`
+	// TopicFeeds 是采集话题问题时请求的列表: "top_activity", "essence" 或 "timeline_question", 默认全部
+	TopicFeeds []string `json:"topicFeeds"`
+	// Keywords 是搜索模式的关键词, 每个关键词依次搜索 SearchTypes 中的类型:
+	// "people", "topic" 或 "question", 默认全部
+	Keywords    []string `json:"keywords"`
+	SearchTypes []string `json:"searchTypes"`
+	// 每个问题, 用户, 专栏, 话题列表, 评论列表或搜索结果最多请求的页数, 0 表示不限制
+	MaxPages int `json:"maxPages"`
+}
+
+// PriorityConfig 是 urlToken 分数的权重, 分数在发现 urlToken 时计算:
+// followerWeight*log10(1+粉丝数) + answerWeight*log10(1+回答数) - depthWeight*深度 - orgPenalty(机构账号)
+type PriorityConfig struct {
+	FollowerWeight float64 `json:"followerWeight"`
+	AnswerWeight   float64 `json:"answerWeight"`
+	DepthWeight    float64 `json:"depthWeight"`
+	OrgPenalty     float64 `json:"orgPenalty"`
+}
+
+// APIConfig 中的接口模板使用 %s 作为 url token 或话题 id 的占位符,
+// 以 "/" 开头的模板会拼接在 BaseURL 之后.
+type APIConfig struct {
+	BaseURL      string `json:"baseURL"`
+	CookieDomain string `json:"cookieDomain"`
+
+	FolloweeAPI    string `json:"followeeAPI"`
+	FollowerAPI    string `json:"followerAPI"`
+	UserSumInfoAPI string `json:"userSumInfoAPI"`
+	TopicIDAPI     string `json:"topicIDAPI"`
+	PeopleAPI      string `json:"peopleAPI"`
+	TopicWebPage   string `json:"topicWebPage"`
+	TopicAPI       string `json:"topicAPI"`
+
+	QuestionAnswersAPI string `json:"questionAnswersAPI"`
+	MemberAnswersAPI   string `json:"memberAnswersAPI"`
+	MemberArticlesAPI  string `json:"memberArticlesAPI"`
+	MemberColumnsAPI   string `json:"memberColumnsAPI"`
+	ColumnArticlesAPI  string `json:"columnArticlesAPI"`
+	TopicTopAPI        string `json:"topicTopAPI"`
+	TopicEssenceAPI    string `json:"topicEssenceAPI"`
+	TopicTimelineAPI   string `json:"topicTimelineAPI"`
+	AnswerCommentsAPI  string `json:"answerCommentsAPI"`
+	ArticleCommentsAPI string `json:"articleCommentsAPI"`
+	ChildCommentsAPI   string `json:"childCommentsAPI"`
+	HotListAPI         string `json:"hotListAPI"`
+	// 搜索接口的 %s 是转义后的关键词
+	SearchPeopleAPI   string `json:"searchPeopleAPI"`
+	SearchTopicAPI    string `json:"searchTopicAPI"`
+	SearchQuestionAPI string `json:"searchQuestionAPI"`
+
+	// include 字段列表, 会以 include= 参数附加在对应的接口上
+	FollowInclude      string `json:"followInclude"`
+	UserSumInfoInclude string `json:"userSumInfoInclude"`
+	TopicInclude       string `json:"topicInclude"`
+	AnswerInclude      string `json:"answerInclude"`
+	// MemberAnswerInclude 默认不包含回答内容
+	MemberAnswerInclude string `json:"memberAnswerInclude"`
+	ArticleInclude      string `json:"articleInclude"`
+	ColumnInclude       string `json:"columnInclude"`
+	TopicFeedInclude    string `json:"topicFeedInclude"`
 }
 
 type EmailConfig struct {
 	User  string `json:"user"`
 	Token string `json:"token"`
 }
+
+// HTTPConfig 是 http 请求路径上的可选配置, 可以不配置.
+type HTTPConfig struct {
+	// 时间格式同 time.ParseDuration, 为空时使用默认值
+	Timeout               string `json:"timeout"`
+	DialTimeout           string `json:"dialTimeout"`
+	TLSHandshakeTimeout   string `json:"tlsHandshakeTimeout"`
+	ResponseHeaderTimeout string `json:"responseHeaderTimeout"`
+	IdleConnTimeout       string `json:"idleConnTimeout"`
+
+	MaxIdleConns        int `json:"maxIdleConns"`
+	MaxIdleConnsPerHost int `json:"maxIdleConnsPerHost"`
+	// MaxBodySize 响应内容的最大字节数
+	MaxBodySize int64 `json:"maxBodySize"`
+	// MinInterval 所有请求之间的最小间隔, robots.txt 的 Crawl-delay 更大时使用后者
+	MinInterval string `json:"minInterval"`
+	// UserAgent 所有请求使用的 User-Agent, 也用于匹配 robots.txt 中的 User-agent.
+	// 为空时使用浏览器的 User-Agent.
+	UserAgent string `json:"userAgent"`
+
+	Cache  *CacheConfig  `json:"cache"`
+	Record *RecordConfig `json:"record"`
+	Robots *RobotsConfig `json:"robots"`
+}
+
+// CacheConfig 用于开发时把响应缓存到磁盘, 避免反复请求知乎.
+type CacheConfig struct {
+	Dir string `json:"dir"`
+	// TTL 为空表示永不过期
+	TTL string `json:"ttl"`
+	// Offline 为 true 时只从缓存读取, 未命中则报错
+	Offline bool `json:"offline"`
+}
+
+// RecordConfig 用于录制或回放请求, 以便在本地重现线上问题.
+type RecordConfig struct {
+	// Mode 为 "record" 或 "replay"
+	Mode string `json:"mode"`
+	Dir  string `json:"dir"`
+}
+
+// RobotsConfig 开启后每个请求都需要先通过目标 host 的 robots.txt 检查.
+type RobotsConfig struct {
+	Enable bool `json:"enable"`
+	// TTL robots.txt 的缓存时间, 默认 24h
+	TTL string `json:"ttl"`
+}
@@ -1,25 +1,122 @@
 package modules
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 
 	_ "github.com/go-sql-driver/mysql"
 )
 
 const (
-	urlTokenTable         = "urlToken"
-	urlTokenProgressTable = "urlTokenProgress"
-	topicIDTable          = "topicID"
-	topicIDProgressTable  = "topicIDProgress"
-	topicTable            = "topic"
-	topicProgressTable    = "topicProgress"
-	industryTable         = "industry"
-	peopleTable           = "people"
-	peopleProgressTable   = "peopleProgress"
+	urlTokenTable              = "urlToken"
+	urlTokenProgressTable      = "urlTokenProgress"
+	priorityProgressTable      = "urlTokenPriorityProgress"
+	topicIDTable               = "topicID"
+	topicIDProgressTable       = "topicIDProgress"
+	topicTable                 = "topic"
+	topicProgressTable         = "topicProgress"
+	industryTable              = "industry"
+	peopleTable                = "people"
+	peopleProgressTable        = "peopleProgress"
+	jobRunTable                = "jobRun"
+	topicIDRootTable           = "topicIDRoot"
+	questionTable              = "question"
+	answerTable                = "answer"
+	answerProgressTable        = "answerProgress"
+	userAnswerProgressTable    = "userAnswerProgress"
+	articleTable               = "article"
+	columnTable                = "column"
+	userArticleProgressTable   = "userArticleProgress"
+	columnArticleProgressTable = "columnArticleProgress"
+	topicQuestionTable         = "topicQuestion"
+	topicQuestionProgressTable = "topicQuestionProgress"
+	commentTable               = "comment"
+	commentProgressTable       = "commentProgress"
+	hotListTable               = "hotList"
+	searchProgressTable        = "searchProgress"
 )
 
+// Store 是采集使用的数据层, DataSource 是基于 MySQL 的实现, 测试中使用内存实现.
+// Get 方法没有数据时返回 sql.ErrNoRows, Insert 方法忽略或更新已存在的数据.
+type Store interface {
+	Close() error
+
+	InsertURLTokens(ctx context.Context, urlTokens []*URLToken) ([]*URLToken, error)
+	GetURLToken(ctx context.Context, offset uint64) (*URLToken, error)
+	GetURLTokenByID(ctx context.Context, id uint64) (*URLToken, bool, error)
+	GetFrontier(ctx context.Context, maxDepth int) (*URLToken, error)
+	MarkURLTokenExpanded(ctx context.Context, id uint64) error
+	GetURLTokenOffset(ctx context.Context, urlTokenID uint64) (uint64, error)
+	GetURLTokenProgress(ctx context.Context) (*URLTokenProgress, error)
+	InsertURLTokenProgress(ctx context.Context, utp *URLTokenProgress) error
+	GetPriorityProgress(ctx context.Context) (*URLTokenProgress, error)
+	InsertPriorityProgress(ctx context.Context, utp *URLTokenProgress) error
+	CountURLToken(ctx context.Context) (uint64, error)
+
+	InsertTopicsID(ctx context.Context, topicsID []*TopicID) error
+	GetTopicID(ctx context.Context, offset uint64) (*TopicID, error)
+	GetTopicIDOffset(ctx context.Context, topicID uint64) (uint64, error)
+	GetTopicIDProgress(ctx context.Context) (*TopicIDProgress, error)
+	InsertTopicIDProgress(ctx context.Context, tip *TopicIDProgress) error
+	InsertTopic(ctx context.Context, tt *TopicTable) error
+	GetTopicProgress(ctx context.Context) (*TopicProgress, error)
+	InsertTopicProgress(ctx context.Context, tp *TopicProgress) error
+	InsertTopicIDRoots(ctx context.Context, roots []*TopicIDRoot) error
+	GetTopicIDRootFrontier(ctx context.Context, rootTopicIDs []string, maxDepth int) (*TopicIDRoot, error)
+	MarkTopicIDRootExpanded(ctx context.Context, id uint64) error
+
+	InsertIndustry(ctx context.Context, industry string) (uint64, error)
+	InsertPeople(ctx context.Context, people *People) error
+	MarkPeopleFailed(ctx context.Context, urlTokenID uint64, gone bool) error
+	GetStalePeople(ctx context.Context, before, failedBefore time.Time, limit int) ([]*URLToken, error)
+	GetPeopleProgress(ctx context.Context) (*PeopleProgress, error)
+	InsertPeopleProgress(ctx context.Context, pp *PeopleProgress) error
+
+	GetLastJobRun(ctx context.Context, name string) (*JobRun, error)
+	InsertJobRun(ctx context.Context, jr *JobRun) error
+
+	InsertQuestions(ctx context.Context, questions []*Question) error
+	GetQuestion(ctx context.Context, offset uint64) (*Question, error)
+	GetQuestionByQuestionID(ctx context.Context, questionID string) (*Question, error)
+	GetQuestionOffset(ctx context.Context, id uint64) (uint64, error)
+	InsertTopicQuestions(ctx context.Context, topicQuestions []*TopicQuestion) error
+	GetTopicQuestionProgress(ctx context.Context) (*TopicQuestionProgress, error)
+	InsertTopicQuestionProgress(ctx context.Context, tqp *TopicQuestionProgress) error
+
+	InsertAnswers(ctx context.Context, answers []*AnswerTable) error
+	GetAnswer(ctx context.Context, offset uint64) (*AnswerTable, error)
+	GetAnswerOffset(ctx context.Context, id uint64) (uint64, error)
+	GetAnswerProgress(ctx context.Context) (*AnswerProgress, error)
+	InsertAnswerProgress(ctx context.Context, ap *AnswerProgress) error
+	GetUserAnswerProgress(ctx context.Context) (*UserAnswerProgress, error)
+	InsertUserAnswerProgress(ctx context.Context, uap *UserAnswerProgress) error
+
+	InsertArticles(ctx context.Context, articles []*ArticleTable) error
+	GetArticle(ctx context.Context, offset uint64) (*ArticleTable, error)
+	GetArticleOffset(ctx context.Context, id uint64) (uint64, error)
+	InsertColumns(ctx context.Context, columns []*ColumnTable) error
+	GetColumn(ctx context.Context, offset uint64) (*ColumnTable, error)
+	GetColumnByColumnID(ctx context.Context, columnID string) (*ColumnTable, error)
+	GetColumnOffset(ctx context.Context, id uint64) (uint64, error)
+	GetUserArticleProgress(ctx context.Context) (*UserArticleProgress, error)
+	InsertUserArticleProgress(ctx context.Context, uap *UserArticleProgress) error
+	GetColumnArticleProgress(ctx context.Context) (*ColumnArticleProgress, error)
+	InsertColumnArticleProgress(ctx context.Context, cp *ColumnArticleProgress) error
+
+	InsertComments(ctx context.Context, comments []*CommentTable) error
+	GetCommentProgress(ctx context.Context, resourceType string) (*CommentProgress, error)
+	InsertCommentProgress(ctx context.Context, cp *CommentProgress) error
+
+	InsertHotList(ctx context.Context, hotList []*HotListTable) error
+	GetSearchProgress(ctx context.Context) (*SearchProgress, error)
+	InsertSearchProgress(ctx context.Context, sp *SearchProgress) error
+}
+
+var _ Store = (*DataSource)(nil)
+
 func NewDataSource(config *MySQLConfig) (*DataSource, error) {
 	if config == nil {
 		return nil, fmt.Errorf("invalid mysql config")
@@ -46,83 +143,134 @@ func (ds *DataSource) Close() error {
 	return ds.db.Close()
 }
 
-func (ds *DataSource) InsertURLTokens(urlTokens []string) error {
+// InsertURLTokens 返回新插入的 urlToken, 已存在的会被忽略并保留原来的深度和分数
+func (ds *DataSource) InsertURLTokens(ctx context.Context, urlTokens []*URLToken) ([]*URLToken, error) {
 	db := ds.db
 
-	queryInsert := fmt.Sprintf(`INSERT INTO %s (urlToken) VALUES (?)`, urlTokenTable)
-	stmtInsert, err := db.Prepare(queryInsert)
+	queryInsert := fmt.Sprintf(`INSERT INTO %s (urlToken,depth,score) VALUES (?,?,?)`, urlTokenTable)
+	stmtInsert, err := db.PrepareContext(ctx, queryInsert)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer stmtInsert.Close()
 
+	var inserted []*URLToken
 	for _, urlToken := range urlTokens {
-		if _, err := stmtInsert.Exec(urlToken); err != nil {
+		result, err := stmtInsert.ExecContext(ctx, urlToken.ToInsert()...)
+		if err != nil {
 			if strings.HasPrefix(err.Error(), "Error 1062: Duplicate entry") {
 				continue
 			}
-			return err
+			return inserted, err
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return inserted, err
 		}
+		urlToken.ID = uint64(id)
+		inserted = append(inserted, urlToken)
 	}
 
-	return nil
+	return inserted, nil
 }
 
-func (ds *DataSource) GetURLToken(offset uint64) (*URLToken, error) {
+func (ds *DataSource) GetURLToken(ctx context.Context, offset uint64) (*URLToken, error) {
 	ut := &URLToken{}
 	//query := fmt.Sprintf(`SELECT id,urlToken FROM %s ORDER BY id LIMIT ?,1`, urlTokenTable)
-	query := fmt.Sprintf(`SELECT id,urlToken FROM %s WHERE id>? ORDER BY id LIMIT 0,1`, urlTokenTable)
-	row := ds.db.QueryRow(query, offset)
+	query := fmt.Sprintf(`SELECT id,urlToken,depth,score FROM %s WHERE id>? ORDER BY id LIMIT 0,1`, urlTokenTable)
+	row := ds.db.QueryRowContext(ctx, query, offset)
 	return ut, row.Scan(ut.ToScan()...)
 }
 
-func (ds *DataSource) GetURLTokenOffset(urlTokenID uint64) (uint64, error) {
+func (ds *DataSource) GetURLTokenByID(ctx context.Context, id uint64) (*URLToken, bool, error) {
+	ut := &URLToken{}
+	var expanded bool
+	query := fmt.Sprintf(`SELECT id,urlToken,depth,score,expanded FROM %s WHERE id=?`, urlTokenTable)
+	row := ds.db.QueryRowContext(ctx, query, id)
+	return ut, expanded, row.Scan(append(ut.ToScan(), &expanded)...)
+}
+
+// GetFrontier 返回分数最高的未展开的 urlToken, maxDepth 为 0 时不限制深度.
+// 依赖 urlToken 上的 (expanded, score) 索引, 见 migrations/004_url_token_priority.sql
+func (ds *DataSource) GetFrontier(ctx context.Context, maxDepth int) (*URLToken, error) {
+	ut := &URLToken{}
+	query := fmt.Sprintf(`SELECT id,urlToken,depth,score FROM %s
+WHERE expanded=0 AND (?=0 OR depth<?) ORDER BY score DESC,id LIMIT 1`, urlTokenTable)
+	row := ds.db.QueryRowContext(ctx, query, maxDepth, maxDepth)
+	return ut, row.Scan(ut.ToScan()...)
+}
+
+// MarkURLTokenExpanded 在 urlToken 的关注和粉丝都保存后调用
+func (ds *DataSource) MarkURLTokenExpanded(ctx context.Context, id uint64) error {
+	query := fmt.Sprintf(`UPDATE %s SET expanded=1 WHERE id=?`, urlTokenTable)
+	_, err := ds.db.ExecContext(ctx, query, id)
+	return err
+}
+
+func (ds *DataSource) GetURLTokenOffset(ctx context.Context, urlTokenID uint64) (uint64, error) {
 	var offset uint64
 	query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE id<? ORDER BY id", urlTokenTable)
-	row := ds.db.QueryRow(query, urlTokenID)
+	row := ds.db.QueryRowContext(ctx, query, urlTokenID)
 	return offset, row.Scan(&offset)
 }
 
-func (ds *DataSource) GetURLTokenProgress() (*URLTokenProgress, error) {
+func (ds *DataSource) GetURLTokenProgress(ctx context.Context) (*URLTokenProgress, error) {
 	utp := &URLTokenProgress{}
 	query := fmt.Sprintf(`SELECT id,urlTokenID,nextFolloweeURL,nextFollowerURL
 FROM %s ORDER BY id DESC LIMIT 1`, urlTokenProgressTable)
-	row := ds.db.QueryRow(query)
+	row := ds.db.QueryRowContext(ctx, query)
 	return utp, row.Scan(utp.ToScan()...)
 }
 
-func (ds *DataSource) InsertURLTokenProgress(utp *URLTokenProgress) error {
+func (ds *DataSource) InsertURLTokenProgress(ctx context.Context, utp *URLTokenProgress) error {
 	query := fmt.Sprintf(`INSERT INTO %s (urlTokenID,nextFolloweeURL,nextFollowerURL) VALUES (?,?,?)`,
 		urlTokenProgressTable)
-	_, err := ds.db.Exec(query, utp.ToInsert()...)
+	_, err := ds.db.ExecContext(ctx, query, utp.ToInsert()...)
+	return err
+}
+
+// GetPriorityProgress 返回按优先级展开时的进度, 和按插入顺序展开的 urlTokenProgress 分开保存
+func (ds *DataSource) GetPriorityProgress(ctx context.Context) (*URLTokenProgress, error) {
+	utp := &URLTokenProgress{}
+	query := fmt.Sprintf(`SELECT id,urlTokenID,nextFolloweeURL,nextFollowerURL
+FROM %s ORDER BY id DESC LIMIT 1`, priorityProgressTable)
+	row := ds.db.QueryRowContext(ctx, query)
+	return utp, row.Scan(utp.ToScan()...)
+}
+
+func (ds *DataSource) InsertPriorityProgress(ctx context.Context, utp *URLTokenProgress) error {
+	query := fmt.Sprintf(`INSERT INTO %s (urlTokenID,nextFolloweeURL,nextFollowerURL) VALUES (?,?,?)`,
+		priorityProgressTable)
+	_, err := ds.db.ExecContext(ctx, query, utp.ToInsert()...)
 	return err
 }
 
-func (ds *DataSource) Truncate(tableName string) error {
+func (ds *DataSource) Truncate(ctx context.Context, tableName string) error {
 	query := fmt.Sprintf(`TRUNCATE TABLE %s`, tableName)
-	_, err := ds.db.Exec(query)
+	_, err := ds.db.ExecContext(ctx, query)
 	return err
 }
 
-func (ds *DataSource) CountURLToken() (count uint64, err error) {
+func (ds *DataSource) CountURLToken(ctx context.Context) (count uint64, err error) {
 	query := fmt.Sprintf(`SELECT COUNT(*) FROM %s`, urlTokenTable)
-	row := ds.db.QueryRow(query)
+	row := ds.db.QueryRowContext(ctx, query)
 
 	return count, row.Scan(&count)
 }
 
-func (ds *DataSource) InsertTopicsID(topicsID []*TopicID) error {
+func (ds *DataSource) InsertTopicsID(ctx context.Context, topicsID []*TopicID) error {
 	db := ds.db
 
 	queryInsert := fmt.Sprintf(`INSERT INTO %s (topicID,name) VALUES (?,?)`, topicIDTable)
-	stmtInsert, err := db.Prepare(queryInsert)
+	stmtInsert, err := db.PrepareContext(ctx, queryInsert)
 	if err != nil {
 		return err
 	}
 	defer stmtInsert.Close()
 
 	for _, topicID := range topicsID {
-		if _, err := stmtInsert.Exec(topicID.ToInsert()...); err != nil {
+		if _, err := stmtInsert.ExecContext(ctx, topicID.ToInsert()...); err != nil {
 			if strings.HasPrefix(err.Error(), "Error 1062: Duplicate entry") {
 				continue
 			}
@@ -133,78 +281,69 @@ func (ds *DataSource) InsertTopicsID(topicsID []*TopicID) error {
 	return nil
 }
 
-func (ds *DataSource) GetTopicID(offset uint64) (*TopicID, error) {
+func (ds *DataSource) GetTopicID(ctx context.Context, offset uint64) (*TopicID, error) {
 	ti := &TopicID{}
 	query := fmt.Sprintf(`SELECT id,topicID,name FROM %s ORDER BY id LIMIT ?,1`, topicIDTable)
-	row := ds.db.QueryRow(query, offset)
+	row := ds.db.QueryRowContext(ctx, query, offset)
 	return ti, row.Scan(ti.ToScan()...)
 }
 
-func (ds *DataSource) GetTopicIDOffset(topicID uint64) (uint64, error) {
+func (ds *DataSource) GetTopicIDOffset(ctx context.Context, topicID uint64) (uint64, error) {
 	var offset uint64
 	query := fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE id<? ORDER BY id`, topicIDTable)
-	row := ds.db.QueryRow(query, topicID)
+	row := ds.db.QueryRowContext(ctx, query, topicID)
 	return offset, row.Scan(&offset)
 }
 
-func (ds *DataSource) GetTopicIDProgress() (*TopicIDProgress, error) {
+func (ds *DataSource) GetTopicIDProgress(ctx context.Context) (*TopicIDProgress, error) {
 	tip := &TopicIDProgress{}
 	query := fmt.Sprintf(`SELECT id,topicID,nextTopicIDURL
 FROM %s ORDER BY id DESC LIMIT 1`, topicIDProgressTable)
-	row := ds.db.QueryRow(query)
+	row := ds.db.QueryRowContext(ctx, query)
 	return tip, row.Scan(tip.ToScan()...)
 }
 
-func (ds *DataSource) InsertTopicIDProgress(tip *TopicIDProgress) error {
+func (ds *DataSource) InsertTopicIDProgress(ctx context.Context, tip *TopicIDProgress) error {
 	query := fmt.Sprintf(`INSERT INTO %s (topicID,nextTopicIDURL) VALUES (?,?)`, topicIDProgressTable)
-	_, err := ds.db.Exec(query, tip.ToInsert()...)
+	_, err := ds.db.ExecContext(ctx, query, tip.ToInsert()...)
 	return err
 }
 
-func (ds *DataSource) InsertTopic(tt *TopicTable) error {
-	db := ds.db
-
-	querySelect := fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE topicID=?`, topicTable)
-	row := db.QueryRow(querySelect, tt.TopicID)
-
-	var count int
-	if err := row.Scan(&count); err != nil {
-		return err
-	}
-	if count != 0 {
-		return nil
-	}
-
-	queryInsert := fmt.Sprintf(`INSERT INTO %s (topicID,followerCount,questionCount) VALUES (?,?,?)`, topicTable)
-	_, err := db.Exec(queryInsert, tt.TopicID, tt.FollowerCount, tt.QuestionCount)
+// InsertTopic 已存在的话题会更新关注数和问题数, 从页面采集时没有的字段保留原来的值 (见 migrations/007)
+func (ds *DataSource) InsertTopic(ctx context.Context, tt *TopicTable) error {
+	query := fmt.Sprintf(`INSERT INTO %s (topicID,followerCount,questionCount,name,introduction,avatarURL,bestAnswerersCount) VALUES (?,?,?,?,?,?,?)
+ON DUPLICATE KEY UPDATE followerCount=VALUES(followerCount),questionCount=VALUES(questionCount),name=IF(VALUES(name)='',name,VALUES(name)),
+introduction=IF(VALUES(introduction)='',introduction,VALUES(introduction)),avatarURL=IF(VALUES(avatarURL)='',avatarURL,VALUES(avatarURL)),
+bestAnswerersCount=IF(VALUES(bestAnswerersCount)=0,bestAnswerersCount,VALUES(bestAnswerersCount))`, topicTable)
+	_, err := ds.db.ExecContext(ctx, query, tt.ToInsert()...)
 	return err
 }
 
-func (ds *DataSource) GetTopicProgress() (*TopicProgress, error) {
+func (ds *DataSource) GetTopicProgress(ctx context.Context) (*TopicProgress, error) {
 	query := fmt.Sprintf(`SELECT id,topicID FROM %s ORDER BY id DESC LIMIT 1`, topicProgressTable)
-	row := ds.db.QueryRow(query)
+	row := ds.db.QueryRowContext(ctx, query)
 	tp := &TopicProgress{}
 	return tp, row.Scan(tp.ToScan()...)
 }
 
-func (ds *DataSource) InsertTopicProgress(tp *TopicProgress) error {
+func (ds *DataSource) InsertTopicProgress(ctx context.Context, tp *TopicProgress) error {
 	query := fmt.Sprintf(`INSERT INTO %s (topicID) VALUES (?)`, topicProgressTable)
-	_, err := ds.db.Exec(query, tp.ToInsert()...)
+	_, err := ds.db.ExecContext(ctx, query, tp.ToInsert()...)
 	return err
 }
 
-func (ds *DataSource) InsertIndustry(industry string) (uint64, error) {
+func (ds *DataSource) InsertIndustry(ctx context.Context, industry string) (uint64, error) {
 	db := ds.db
 
 	querySelect := fmt.Sprintf(`SELECT id,name FROM %s WHERE name=?`, industryTable)
 	queryInsert := fmt.Sprintf(`INSERT INTO %s (name) VALUES (?)`, industryTable)
 
-	row := db.QueryRow(querySelect, industry)
+	row := db.QueryRowContext(ctx, querySelect, industry)
 
 	ind := &Industry{}
 	err := row.Scan(ind.ToScan()...)
 	if err == sql.ErrNoRows {
-		if res, err := db.Exec(queryInsert, industry); err != nil {
+		if res, err := db.ExecContext(ctx, queryInsert, industry); err != nil {
 			return 0, err
 		} else {
 			id, err := res.LastInsertId()
@@ -217,29 +356,446 @@ func (ds *DataSource) InsertIndustry(industry string) (uint64, error) {
 	}
 }
 
-func (ds *DataSource) InsertPeople(people *People) error {
+// InsertPeople 同时在 urlToken 中记录采集时间和粉丝数, 用于查找需要刷新的用户
+func (ds *DataSource) InsertPeople(ctx context.Context, people *People) error {
 	if people == nil {
 		return fmt.Errorf("invalid people data")
 	}
+	if people.CrawlTime.IsZero() {
+		people.CrawlTime = time.Now()
+	}
 
-	query := fmt.Sprintf(`INSERT INTO %s (urlTokenID,name,headline,description,gender,followeeCount,followerCount,answerCount,questionCount,articlesCount,columnsCount,industry,address,school,major,entranceYear,graduationYear,company,job) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)`, peopleTable)
-	_, err := ds.db.Exec(query, people.ToInsert()...)
+	tx, err := ds.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// urlTokenID 有唯一索引, 重新采集会更新原来的数据
+	query := fmt.Sprintf(`INSERT INTO %s (urlTokenID,name,headline,description,gender,followeeCount,followerCount,answerCount,questionCount,articlesCount,columnsCount,industry,address,school,major,entranceYear,graduationYear,company,job,crawlTime) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)
+ON DUPLICATE KEY UPDATE name=VALUES(name),headline=VALUES(headline),description=VALUES(description),gender=VALUES(gender),followeeCount=VALUES(followeeCount),followerCount=VALUES(followerCount),answerCount=VALUES(answerCount),questionCount=VALUES(questionCount),articlesCount=VALUES(articlesCount),columnsCount=VALUES(columnsCount),industry=VALUES(industry),address=VALUES(address),school=VALUES(school),major=VALUES(major),entranceYear=VALUES(entranceYear),graduationYear=VALUES(graduationYear),company=VALUES(company),job=VALUES(job),crawlTime=VALUES(crawlTime)`, peopleTable)
+	if _, err := tx.ExecContext(ctx, query, people.ToInsert()...); err != nil {
+		return err
+	}
+
+	queryUpdate := fmt.Sprintf(`UPDATE %s SET crawlTime=?,followerCount=?,gone=0,failTime=NULL WHERE id=?`, urlTokenTable)
+	if _, err := tx.ExecContext(ctx, queryUpdate, people.CrawlTime, people.FollowerCount, people.URLTokenID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// MarkPeopleFailed 记录采集失败的时间, gone 为 true 时该用户不会再被刷新
+func (ds *DataSource) MarkPeopleFailed(ctx context.Context, urlTokenID uint64, gone bool) error {
+	query := fmt.Sprintf(`UPDATE %s SET gone=?,failTime=? WHERE id=?`, urlTokenTable)
+	_, err := ds.db.ExecContext(ctx, query, gone, time.Now(), urlTokenID)
 	return err
 }
 
-func (ds *DataSource) GetPeopleProgress() (*PeopleProgress, error) {
+// GetStalePeople 返回最后一次采集早于 before 或从未采集过的用户, 粉丝多的用户在前.
+// 已注销的用户和 failedBefore 之后采集失败的用户会被跳过.
+func (ds *DataSource) GetStalePeople(ctx context.Context, before, failedBefore time.Time, limit int) ([]*URLToken, error) {
+	query := fmt.Sprintf(`SELECT id,urlToken,depth,score FROM %s
+WHERE gone=0 AND (crawlTime IS NULL OR crawlTime<?) AND (failTime IS NULL OR failTime<?)
+ORDER BY followerCount DESC,id LIMIT ?`, urlTokenTable)
+	rows, err := ds.db.QueryContext(ctx, query, before, failedBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var urlTokens []*URLToken
+	for rows.Next() {
+		ut := &URLToken{}
+		if err := rows.Scan(ut.ToScan()...); err != nil {
+			return nil, err
+		}
+		urlTokens = append(urlTokens, ut)
+	}
+	return urlTokens, rows.Err()
+}
+
+func (ds *DataSource) GetPeopleProgress(ctx context.Context) (*PeopleProgress, error) {
 	query := fmt.Sprintf(`SELECT id,urlTokenID FROM %s ORDER BY id DESC LIMIT 1`, peopleProgressTable)
 	pp := &PeopleProgress{}
-	row := ds.db.QueryRow(query)
+	row := ds.db.QueryRowContext(ctx, query)
 	return pp, row.Scan(pp.ToScan()...)
 }
 
-func (ds *DataSource) InsertPeopleProgress(pp *PeopleProgress) error {
+func (ds *DataSource) InsertPeopleProgress(ctx context.Context, pp *PeopleProgress) error {
 	if pp == nil {
 		return fmt.Errorf("found invalid peopleProgress when insert")
 	}
 
 	query := fmt.Sprintf("INSERT INTO %s (urlTokenID) VALUES (?)", peopleProgressTable)
-	_, err := ds.db.Exec(query, pp.URLTokenID)
+	_, err := ds.db.ExecContext(ctx, query, pp.URLTokenID)
+	return err
+}
+
+// GetLastJobRun 返回任务最近一次运行的记录
+func (ds *DataSource) GetLastJobRun(ctx context.Context, name string) (*JobRun, error) {
+	query := fmt.Sprintf(`SELECT id,name,startTime,endTime FROM %s WHERE name=? ORDER BY id DESC LIMIT 1`,
+		jobRunTable)
+	jr := &JobRun{}
+	row := ds.db.QueryRowContext(ctx, query, name)
+	return jr, row.Scan(jr.ToScan()...)
+}
+
+func (ds *DataSource) InsertJobRun(ctx context.Context, jr *JobRun) error {
+	query := fmt.Sprintf("INSERT INTO %s (name,startTime,endTime) VALUES (?,?,?)", jobRunTable)
+	_, err := ds.db.ExecContext(ctx, query, jr.ToInsert()...)
+	return err
+}
+
+// InsertTopicIDRoots 忽略已存在的 (topicID, rootTopicID), 按 BFS 顺序插入时保留的是最小深度
+func (ds *DataSource) InsertTopicIDRoots(ctx context.Context, roots []*TopicIDRoot) error {
+	queryInsert := fmt.Sprintf(`INSERT INTO %s (topicID,rootTopicID,depth) VALUES (?,?,?)`, topicIDRootTable)
+	stmtInsert, err := ds.db.PrepareContext(ctx, queryInsert)
+	if err != nil {
+		return err
+	}
+	defer stmtInsert.Close()
+
+	for _, root := range roots {
+		if _, err := stmtInsert.ExecContext(ctx, root.ToInsert()...); err != nil {
+			if strings.HasPrefix(err.Error(), "Error 1062: Duplicate entry") {
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// GetTopicIDRootFrontier 返回 rootTopicIDs 下深度最小的未展开的话题, maxDepth 为 0 时不限制深度
+func (ds *DataSource) GetTopicIDRootFrontier(ctx context.Context, rootTopicIDs []string, maxDepth int) (*TopicIDRoot, error) {
+	if len(rootTopicIDs) == 0 {
+		return nil, fmt.Errorf("empty root topic id")
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(rootTopicIDs)), ",")
+	query := fmt.Sprintf(`SELECT id,topicID,rootTopicID,depth FROM %s
+WHERE expanded=0 AND rootTopicID IN (%s) AND (?=0 OR depth<?) ORDER BY depth,id LIMIT 1`,
+		topicIDRootTable, placeholders)
+
+	var args []interface{}
+	for _, id := range rootTopicIDs {
+		args = append(args, id)
+	}
+	args = append(args, maxDepth, maxDepth)
+
+	tir := &TopicIDRoot{}
+	row := ds.db.QueryRowContext(ctx, query, args...)
+	return tir, row.Scan(tir.ToScan()...)
+}
+
+func (ds *DataSource) MarkTopicIDRootExpanded(ctx context.Context, id uint64) error {
+	query := fmt.Sprintf(`UPDATE %s SET expanded=1 WHERE id=?`, topicIDRootTable)
+	_, err := ds.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// InsertQuestions 已存在的问题只在有新数据时更新, 从回答中得到的问题只有标题
+func (ds *DataSource) InsertQuestions(ctx context.Context, questions []*Question) error {
+	queryInsert := fmt.Sprintf(`INSERT INTO %s (questionID,title,answerCount,followerCount,createdTime) VALUES (?,?,?,?,?)
+ON DUPLICATE KEY UPDATE title=IF(VALUES(title)='',title,VALUES(title)),answerCount=IF(VALUES(answerCount)=0,answerCount,VALUES(answerCount)),
+followerCount=IF(VALUES(followerCount)=0,followerCount,VALUES(followerCount)),createdTime=IFNULL(VALUES(createdTime),createdTime)`, questionTable)
+	stmtInsert, err := ds.db.PrepareContext(ctx, queryInsert)
+	if err != nil {
+		return err
+	}
+	defer stmtInsert.Close()
+
+	for _, question := range questions {
+		if _, err := stmtInsert.ExecContext(ctx, question.ToInsert()...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InsertTopicQuestions 忽略已存在的 (topicID, questionID)
+func (ds *DataSource) InsertTopicQuestions(ctx context.Context, topicQuestions []*TopicQuestion) error {
+	queryInsert := fmt.Sprintf(`INSERT INTO %s (topicID,questionID,feed) VALUES (?,?,?)`, topicQuestionTable)
+	stmtInsert, err := ds.db.PrepareContext(ctx, queryInsert)
+	if err != nil {
+		return err
+	}
+	defer stmtInsert.Close()
+
+	for _, tq := range topicQuestions {
+		if _, err := stmtInsert.ExecContext(ctx, tq.ToInsert()...); err != nil {
+			if strings.HasPrefix(err.Error(), "Error 1062: Duplicate entry") {
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (ds *DataSource) GetTopicQuestionProgress(ctx context.Context) (*TopicQuestionProgress, error) {
+	tqp := &TopicQuestionProgress{}
+	query := fmt.Sprintf(`SELECT id,topicID,feed,nextQuestionURL FROM %s ORDER BY id DESC LIMIT 1`,
+		topicQuestionProgressTable)
+	row := ds.db.QueryRowContext(ctx, query)
+	return tqp, row.Scan(tqp.ToScan()...)
+}
+
+func (ds *DataSource) InsertTopicQuestionProgress(ctx context.Context, tqp *TopicQuestionProgress) error {
+	query := fmt.Sprintf(`INSERT INTO %s (topicID,feed,nextQuestionURL) VALUES (?,?,?)`, topicQuestionProgressTable)
+	_, err := ds.db.ExecContext(ctx, query, tqp.ToInsert()...)
+	return err
+}
+
+func (ds *DataSource) GetQuestion(ctx context.Context, offset uint64) (*Question, error) {
+	q := &Question{}
+	query := fmt.Sprintf(`SELECT id,questionID,title FROM %s ORDER BY id LIMIT ?,1`, questionTable)
+	row := ds.db.QueryRowContext(ctx, query, offset)
+	return q, row.Scan(q.ToScan()...)
+}
+
+func (ds *DataSource) GetQuestionByQuestionID(ctx context.Context, questionID string) (*Question, error) {
+	q := &Question{}
+	query := fmt.Sprintf(`SELECT id,questionID,title FROM %s WHERE questionID=?`, questionTable)
+	row := ds.db.QueryRowContext(ctx, query, questionID)
+	return q, row.Scan(q.ToScan()...)
+}
+
+func (ds *DataSource) GetQuestionOffset(ctx context.Context, id uint64) (uint64, error) {
+	var offset uint64
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE id<?`, questionTable)
+	row := ds.db.QueryRowContext(ctx, query, id)
+	return offset, row.Scan(&offset)
+}
+
+// InsertAnswers 已存在的回答会更新赞同数, 评论数和内容, 没有采集内容或作者 id 时保留原来的值
+func (ds *DataSource) InsertAnswers(ctx context.Context, answers []*AnswerTable) error {
+	queryInsert := fmt.Sprintf(`INSERT INTO %s (answerID,questionID,authorURLToken,urlTokenID,voteupCount,commentCount,createdTime,updatedTime,content) VALUES (?,?,?,?,?,?,?,?,?)
+ON DUPLICATE KEY UPDATE urlTokenID=GREATEST(urlTokenID,VALUES(urlTokenID)),voteupCount=VALUES(voteupCount),commentCount=VALUES(commentCount),updatedTime=VALUES(updatedTime),
+content=IF(VALUES(content)='',content,VALUES(content))`, answerTable)
+	stmtInsert, err := ds.db.PrepareContext(ctx, queryInsert)
+	if err != nil {
+		return err
+	}
+	defer stmtInsert.Close()
+
+	for _, answer := range answers {
+		if _, err := stmtInsert.ExecContext(ctx, answer.ToInsert()...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ds *DataSource) GetAnswer(ctx context.Context, offset uint64) (*AnswerTable, error) {
+	at := &AnswerTable{}
+	query := fmt.Sprintf(`SELECT id,answerID FROM %s ORDER BY id LIMIT ?,1`, answerTable)
+	row := ds.db.QueryRowContext(ctx, query, offset)
+	return at, row.Scan(at.ToScan()...)
+}
+
+func (ds *DataSource) GetAnswerOffset(ctx context.Context, id uint64) (uint64, error) {
+	var offset uint64
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE id<?`, answerTable)
+	row := ds.db.QueryRowContext(ctx, query, id)
+	return offset, row.Scan(&offset)
+}
+
+func (ds *DataSource) GetAnswerProgress(ctx context.Context) (*AnswerProgress, error) {
+	ap := &AnswerProgress{}
+	query := fmt.Sprintf(`SELECT id,questionID,nextAnswerURL FROM %s ORDER BY id DESC LIMIT 1`, answerProgressTable)
+	row := ds.db.QueryRowContext(ctx, query)
+	return ap, row.Scan(ap.ToScan()...)
+}
+
+func (ds *DataSource) InsertAnswerProgress(ctx context.Context, ap *AnswerProgress) error {
+	query := fmt.Sprintf(`INSERT INTO %s (questionID,nextAnswerURL) VALUES (?,?)`, answerProgressTable)
+	_, err := ds.db.ExecContext(ctx, query, ap.ToInsert()...)
+	return err
+}
+
+func (ds *DataSource) GetUserAnswerProgress(ctx context.Context) (*UserAnswerProgress, error) {
+	uap := &UserAnswerProgress{}
+	query := fmt.Sprintf(`SELECT id,urlTokenID,nextAnswerURL FROM %s ORDER BY id DESC LIMIT 1`, userAnswerProgressTable)
+	row := ds.db.QueryRowContext(ctx, query)
+	return uap, row.Scan(uap.ToScan()...)
+}
+
+func (ds *DataSource) InsertUserAnswerProgress(ctx context.Context, uap *UserAnswerProgress) error {
+	query := fmt.Sprintf(`INSERT INTO %s (urlTokenID,nextAnswerURL) VALUES (?,?)`, userAnswerProgressTable)
+	_, err := ds.db.ExecContext(ctx, query, uap.ToInsert()...)
+	return err
+}
+
+// InsertArticles 已存在的文章会更新标题, 赞同数和评论数
+func (ds *DataSource) InsertArticles(ctx context.Context, articles []*ArticleTable) error {
+	queryInsert := fmt.Sprintf(`INSERT INTO %s (articleID,title,columnID,authorURLToken,urlTokenID,voteupCount,commentCount,createdTime,updatedTime) VALUES (?,?,?,?,?,?,?,?,?)
+ON DUPLICATE KEY UPDATE title=VALUES(title),urlTokenID=GREATEST(urlTokenID,VALUES(urlTokenID)),voteupCount=VALUES(voteupCount),commentCount=VALUES(commentCount),updatedTime=VALUES(updatedTime)`, articleTable)
+	stmtInsert, err := ds.db.PrepareContext(ctx, queryInsert)
+	if err != nil {
+		return err
+	}
+	defer stmtInsert.Close()
+
+	for _, article := range articles {
+		if _, err := stmtInsert.ExecContext(ctx, article.ToInsert()...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ds *DataSource) GetArticle(ctx context.Context, offset uint64) (*ArticleTable, error) {
+	at := &ArticleTable{}
+	query := fmt.Sprintf(`SELECT id,articleID FROM %s ORDER BY id LIMIT ?,1`, articleTable)
+	row := ds.db.QueryRowContext(ctx, query, offset)
+	return at, row.Scan(at.ToScan()...)
+}
+
+func (ds *DataSource) GetArticleOffset(ctx context.Context, id uint64) (uint64, error) {
+	var offset uint64
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE id<?`, articleTable)
+	row := ds.db.QueryRowContext(ctx, query, id)
+	return offset, row.Scan(&offset)
+}
+
+// InsertColumns 已存在的专栏只更新不为空的字段, 从文章中得到的专栏只有标题, 配置中的专栏只有 id
+func (ds *DataSource) InsertColumns(ctx context.Context, columns []*ColumnTable) error {
+	queryInsert := fmt.Sprintf("INSERT INTO `%s` (columnID,title,intro,authorURLToken,articlesCount,followers) VALUES (?,?,?,?,?,?)\n"+
+		"ON DUPLICATE KEY UPDATE title=IF(VALUES(title)='',title,VALUES(title)),intro=IF(VALUES(intro)='',intro,VALUES(intro)),"+
+		"authorURLToken=IF(VALUES(authorURLToken)='',authorURLToken,VALUES(authorURLToken)),"+
+		"articlesCount=GREATEST(articlesCount,VALUES(articlesCount)),followers=IF(VALUES(followers)=0,followers,VALUES(followers))",
+		columnTable)
+	stmtInsert, err := ds.db.PrepareContext(ctx, queryInsert)
+	if err != nil {
+		return err
+	}
+	defer stmtInsert.Close()
+
+	for _, column := range columns {
+		if _, err := stmtInsert.ExecContext(ctx, column.ToInsert()...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ds *DataSource) GetColumn(ctx context.Context, offset uint64) (*ColumnTable, error) {
+	ct := &ColumnTable{}
+	query := fmt.Sprintf("SELECT id,columnID,title FROM `%s` ORDER BY id LIMIT ?,1", columnTable)
+	row := ds.db.QueryRowContext(ctx, query, offset)
+	return ct, row.Scan(ct.ToScan()...)
+}
+
+func (ds *DataSource) GetColumnByColumnID(ctx context.Context, columnID string) (*ColumnTable, error) {
+	ct := &ColumnTable{}
+	query := fmt.Sprintf("SELECT id,columnID,title FROM `%s` WHERE columnID=?", columnTable)
+	row := ds.db.QueryRowContext(ctx, query, columnID)
+	return ct, row.Scan(ct.ToScan()...)
+}
+
+func (ds *DataSource) GetColumnOffset(ctx context.Context, id uint64) (uint64, error) {
+	var offset uint64
+	query := fmt.Sprintf("SELECT COUNT(*) FROM `%s` WHERE id<?", columnTable)
+	row := ds.db.QueryRowContext(ctx, query, id)
+	return offset, row.Scan(&offset)
+}
+
+func (ds *DataSource) GetUserArticleProgress(ctx context.Context) (*UserArticleProgress, error) {
+	uap := &UserArticleProgress{}
+	query := fmt.Sprintf(`SELECT id,urlTokenID,nextArticleURL,nextColumnURL FROM %s ORDER BY id DESC LIMIT 1`,
+		userArticleProgressTable)
+	row := ds.db.QueryRowContext(ctx, query)
+	return uap, row.Scan(uap.ToScan()...)
+}
+
+func (ds *DataSource) InsertUserArticleProgress(ctx context.Context, uap *UserArticleProgress) error {
+	query := fmt.Sprintf(`INSERT INTO %s (urlTokenID,nextArticleURL,nextColumnURL) VALUES (?,?,?)`,
+		userArticleProgressTable)
+	_, err := ds.db.ExecContext(ctx, query, uap.ToInsert()...)
+	return err
+}
+
+func (ds *DataSource) GetColumnArticleProgress(ctx context.Context) (*ColumnArticleProgress, error) {
+	cp := &ColumnArticleProgress{}
+	query := fmt.Sprintf(`SELECT id,columnID,nextArticleURL FROM %s ORDER BY id DESC LIMIT 1`,
+		columnArticleProgressTable)
+	row := ds.db.QueryRowContext(ctx, query)
+	return cp, row.Scan(cp.ToScan()...)
+}
+
+func (ds *DataSource) InsertColumnArticleProgress(ctx context.Context, cp *ColumnArticleProgress) error {
+	query := fmt.Sprintf(`INSERT INTO %s (columnID,nextArticleURL) VALUES (?,?)`, columnArticleProgressTable)
+	_, err := ds.db.ExecContext(ctx, query, cp.ToInsert()...)
+	return err
+}
+
+// InsertComments 已存在的评论会更新点赞数, 评论被重新请求时不会重复插入
+func (ds *DataSource) InsertComments(ctx context.Context, comments []*CommentTable) error {
+	queryInsert := fmt.Sprintf(`INSERT INTO %s (commentID,resourceType,resourceID,rootCommentID,authorURLToken,replyToURLToken,content,likeCount,createdTime) VALUES (?,?,?,?,?,?,?,?,?)
+ON DUPLICATE KEY UPDATE likeCount=VALUES(likeCount)`, commentTable)
+	stmtInsert, err := ds.db.PrepareContext(ctx, queryInsert)
+	if err != nil {
+		return err
+	}
+	defer stmtInsert.Close()
+
+	for _, comment := range comments {
+		if _, err := stmtInsert.ExecContext(ctx, comment.ToInsert()...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ds *DataSource) GetCommentProgress(ctx context.Context, resourceType string) (*CommentProgress, error) {
+	cp := &CommentProgress{}
+	query := fmt.Sprintf(`SELECT id,resourceType,resourceID,nextCommentURL FROM %s WHERE resourceType=? ORDER BY id DESC LIMIT 1`,
+		commentProgressTable)
+	row := ds.db.QueryRowContext(ctx, query, resourceType)
+	return cp, row.Scan(cp.ToScan()...)
+}
+
+func (ds *DataSource) InsertCommentProgress(ctx context.Context, cp *CommentProgress) error {
+	query := fmt.Sprintf(`INSERT INTO %s (resourceType,resourceID,nextCommentURL) VALUES (?,?,?)`, commentProgressTable)
+	_, err := ds.db.ExecContext(ctx, query, cp.ToInsert()...)
+	return err
+}
+
+// InsertHotList 在一个事务中插入一次快照的所有条目, 表中不会出现不完整的快照
+func (ds *DataSource) InsertHotList(ctx context.Context, hotList []*HotListTable) error {
+	tx, err := ds.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// rank 是 MySQL 8 的关键字
+	queryInsert := fmt.Sprintf("INSERT INTO %s (snapshotTime,`rank`,questionID,title,heat) VALUES (?,?,?,?,?)", hotListTable)
+	stmtInsert, err := tx.PrepareContext(ctx, queryInsert)
+	if err != nil {
+		return err
+	}
+	defer stmtInsert.Close()
+
+	for _, ht := range hotList {
+		if _, err := stmtInsert.ExecContext(ctx, ht.ToInsert()...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (ds *DataSource) GetSearchProgress(ctx context.Context) (*SearchProgress, error) {
+	sp := &SearchProgress{}
+	query := fmt.Sprintf(`SELECT id,keyword,searchType,nextSearchURL FROM %s ORDER BY id DESC LIMIT 1`, searchProgressTable)
+	row := ds.db.QueryRowContext(ctx, query)
+	return sp, row.Scan(sp.ToScan()...)
+}
+
+func (ds *DataSource) InsertSearchProgress(ctx context.Context, sp *SearchProgress) error {
+	query := fmt.Sprintf(`INSERT INTO %s (keyword,searchType,nextSearchURL) VALUES (?,?,?)`, searchProgressTable)
+	_, err := ds.db.ExecContext(ctx, query, sp.ToInsert()...)
 	return err
 }
@@ -0,0 +1,120 @@
+package modules
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// 调用方使用 errors.Is 判断 APIError 的类别
+var (
+	ErrGone        = errors.New("user or resource gone")
+	ErrNotLoggedIn = errors.New("not logged in")
+	ErrRateLimited = errors.New("rate limited")
+	ErrServer      = errors.New("server error")
+	ErrUnexpected  = errors.New("unexpected response")
+	ErrDisallowed  = errors.New("disallowed by robots.txt")
+)
+
+// 知乎要求验证时返回的错误码
+var blockErrorCodes = map[int]bool{
+	40352: true,
+	40362: true,
+}
+
+// APIError 包含响应状态码和知乎的错误信息 {"error":{"code":..,"message":..,"name":..}}
+type APIError struct {
+	URL        string `json:"-"`
+	StatusCode int    `json:"-"`
+
+	Code     int    `json:"code"`
+	Message  string `json:"message"`
+	Name     string `json:"name"`
+	Redirect string `json:"redirect"`
+
+	kind error
+}
+
+func (ae *APIError) Error() string {
+	return fmt.Sprintf("%s, url: %s, status: %d, code: %d, name: %s, message: %s",
+		ae.kind, ae.URL, ae.StatusCode, ae.Code, ae.Name, ae.Message)
+}
+
+func (ae *APIError) Unwrap() error {
+	return ae.kind
+}
+
+// checkResponse 根据状态码和错误信息对响应分类, 正常的响应返回 nil.
+func checkResponse(resp *http.Response, data []byte) error {
+	ae := &APIError{
+		StatusCode: resp.StatusCode,
+	}
+	if resp.Request != nil {
+		ae.URL = resp.Request.URL.String()
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		envelope := &struct {
+			Error *APIError `json:"error"`
+		}{
+			Error: ae,
+		}
+		// 不是错误信息时 ae 保持不变
+		json.Unmarshal(trimmed, envelope)
+	}
+
+	ae.kind = classify(resp, trimmed, ae)
+	if ae.kind == nil {
+		return nil
+	}
+	if ae.Message == "" {
+		ae.Message = http.StatusText(resp.StatusCode)
+	}
+	return ae
+}
+
+func classify(resp *http.Response, data []byte, ae *APIError) error {
+	// 被拦截的情况优先判断, 需要暂停采集
+	if blockErrorCodes[ae.Code] || strings.Contains(ae.Redirect, "unhuman") {
+		return ErrRateLimited
+	}
+	// client 会跟随跳转, 所以检查最终请求的地址
+	if resp.Request != nil && strings.Contains(resp.Request.URL.Path, "unhuman") {
+		ae.Redirect = resp.Request.URL.String()
+		return ErrRateLimited
+	}
+
+	switch code := resp.StatusCode; {
+	case code == http.StatusUnauthorized:
+		return ErrNotLoggedIn
+	case code == http.StatusForbidden, code == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case code == http.StatusNotFound, code == http.StatusGone:
+		return ErrGone
+	case code >= http.StatusInternalServerError:
+		return ErrServer
+	case code < http.StatusOK || code >= http.StatusMultipleChoices:
+		return ErrUnexpected
+	}
+
+	// 状态码正常但包含错误信息
+	if ae.Code != 0 || ae.Name != "" {
+		return ErrUnexpected
+	}
+
+	// 接口返回了验证页面而不是 json, json 中的回答和评论内容也可能包含这些文字
+	if resp.Request != nil && strings.HasPrefix(resp.Request.URL.Path, "/api/") && !isJSON(data) &&
+		(bytes.Contains(data, []byte("unhuman")) || bytes.Contains(data, []byte("验证码"))) {
+		ae.Message = "verification page"
+		return ErrRateLimited
+	}
+	return nil
+}
+
+func isJSON(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("{")) || bytes.HasPrefix(data, []byte("["))
+}
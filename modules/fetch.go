@@ -0,0 +1,100 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"zhihu/utils"
+
+	"github.com/astaxie/beego/logs"
+)
+
+// maxBlockRetries 是同一个 url 连续被拦截后最多重试的次数
+const maxBlockRetries = 10
+
+// get 请求 url 并返回响应内容.
+// 被知乎拦截时会暂停所有采集, 暂停结束后重新请求同一个 url,
+// 重试 maxBlockRetries 次后依然被拦截时返回错误.
+func (zh *ZhiHu) get(ctx context.Context, url string) ([]byte, error) {
+	for retries := 0; ; retries++ {
+		if err := zh.coolDown.wait(ctx); err != nil {
+			return nil, err
+		}
+		if zh.robots != nil {
+			if err := zh.robots.check(ctx, url); err != nil {
+				return nil, err
+			}
+		}
+		if err := zh.limiter.wait(ctx); err != nil {
+			return nil, err
+		}
+
+		req, err := newRequest(ctx, url, zh.userAgent)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := zh.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		// 多读一个字节用来判断是否超出限制
+		data, err := ioutil.ReadAll(io.LimitReader(resp.Body, zh.maxBodySize+1))
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(data)) > zh.maxBodySize {
+			return nil, fmt.Errorf("response body too large, url: %s, limit: %d", url, zh.maxBodySize)
+		}
+
+		err = checkResponse(resp, data)
+		if err == nil {
+			zh.coolDown.reset()
+			return data, nil
+		}
+		if !errors.Is(err, ErrRateLimited) || retries >= maxBlockRetries {
+			return nil, err
+		}
+		zh.onBlocked(url, err)
+	}
+}
+
+// newRequest 创建 GET 请求, userAgent 为空时使用浏览器的 User-Agent.
+// robots.txt 按同一个 User-Agent 匹配规则, 所以请求都需要通过它创建.
+func newRequest(ctx context.Context, url, userAgent string) (*http.Request, error) {
+	if userAgent == "" {
+		return utils.NewRequestWithUserAgent(ctx, "GET", url, nil)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	return req, nil
+}
+
+// onBlocked 每次暂停只记录和发送一次邮件
+func (zh *ZhiHu) onBlocked(url string, err error) {
+	dur, started := zh.coolDown.trip()
+	if !started {
+		logs.Debug("blocked during cool down: %s, remain: %s", err, dur)
+		return
+	}
+	logs.Warn("blocked by zhihu: %s, cool down: %s", err, dur)
+
+	if zh.emailSender == nil {
+		return
+	}
+	msg := &EmailMsg{
+		Subject: "ZhiHu Blocked",
+		Content: fmt.Sprintf("url: %s, err: %s, cool down: %s", url, err, dur),
+	}
+	if err := zh.emailSender.SendEmail(msg); err != nil {
+		logs.Error("error when send 'ZhiHu Blocked' email: %s", err)
+	}
+}
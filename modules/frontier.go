@@ -0,0 +1,99 @@
+package modules
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/astaxie/beego/logs"
+)
+
+// collectURLTokenByPriority 每次展开分数最高的未展开的 urlToken.
+// 展开完成的 urlToken 会被标记, 中断时把正在展开的 urlToken 的进度保存到 urlTokenPriorityProgress.
+func (zh *ZhiHu) collectURLTokenByPriority(ctx context.Context, job *CollectJob) {
+	ds := zh.dataSource
+
+	var current *URLToken
+	var startFolloweeURL string
+	var startFollowerURL string
+
+	// 继续上次没有展开完成的 urlToken
+	utp, err := ds.GetPriorityProgress(ctx)
+	if err != nil && err != sql.ErrNoRows {
+		logs.Error("error when get urlTokenPriorityProgress: %s", err)
+		return
+	} else if err == nil {
+		ut, expanded, err := ds.GetURLTokenByID(ctx, utp.URLTokenID)
+		if err != nil && err != sql.ErrNoRows {
+			logs.Error("error when get urlToken of progress: %s", err)
+			return
+		} else if err == nil && !expanded {
+			current = ut
+			startFolloweeURL = utp.NextFolloweeURL
+			startFollowerURL = utp.NextFollowerURL
+			logs.Debug("load urlTokenPriorityProgress success")
+		}
+	}
+
+loop:
+	for {
+		if current == nil {
+			ut, err := ds.GetFrontier(ctx, job.config.MaxDepth)
+			if err == sql.ErrNoRows {
+				logs.Info("no url token left to expand")
+				break loop
+			} else if err != nil {
+				logs.Error("error when get frontier: %s", err)
+				break loop
+			}
+			current = ut
+			startFolloweeURL = zh.api.FolloweeURL(ut.URLToken)
+			startFollowerURL = zh.api.FollowerURL(ut.URLToken)
+		}
+
+		if job.reachMaxNodes() {
+			break loop
+		}
+
+		var err error
+		startFolloweeURL, startFollowerURL, err = zh.expandURLToken(ctx, job, startFolloweeURL, startFollowerURL, current.Depth+1)
+		if job.reachMaxNodes() {
+			break loop
+		}
+
+		select {
+		case <-ctx.Done():
+			logs.Info("stop collect url token, urlToken: %s", current.URLToken)
+			break loop
+		default:
+		}
+
+		// 未标记的 urlToken 会再次被 GetFrontier 选中, 所以出错时保存进度并停止
+		if err != nil && !errors.Is(err, ErrGone) {
+			logs.Error("stop collect url token: %s, urlToken: %s", err, current.URLToken)
+			break loop
+		}
+
+		if err := ds.MarkURLTokenExpanded(ctx, current.ID); err != nil {
+			logs.Error("error when mark urlToken expanded: %s", err)
+			break loop
+		}
+		current = nil
+	}
+
+	if current == nil {
+		return
+	}
+
+	// ctx 取消后依然需要保存进度
+	ctx = context.Background()
+
+	urlTokenProgress := &URLTokenProgress{
+		URLTokenID:      current.ID,
+		NextFolloweeURL: startFolloweeURL,
+		NextFollowerURL: startFollowerURL,
+	}
+	if err := ds.InsertPriorityProgress(ctx, urlTokenProgress); err != nil {
+		logs.Error("error when insert urlTokenPriorityProgress: %s", err)
+	}
+}
@@ -0,0 +1,106 @@
+package modules
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCoolDown    = 5 * time.Minute
+	defaultMaxCoolDown = 2 * time.Hour
+)
+
+func parseCoolDown(config *ZhiHuConfig) (*coolDown, error) {
+	var base, max time.Duration
+	var err error
+	if config.CoolDown != "" {
+		if base, err = time.ParseDuration(config.CoolDown); err != nil {
+			return nil, err
+		}
+	}
+	if config.MaxCoolDown != "" {
+		if max, err = time.ParseDuration(config.MaxCoolDown); err != nil {
+			return nil, err
+		}
+	} else {
+		max = defaultMaxCoolDown
+	}
+	return newCoolDown(base, max), nil
+}
+
+func newCoolDown(base, max time.Duration) *coolDown {
+	if base <= 0 {
+		base = defaultCoolDown
+	}
+	if max < base {
+		max = base
+	}
+
+	cd := &coolDown{
+		base: base,
+		max:  max,
+	}
+	return cd
+}
+
+// coolDown 是所有采集器共享的暂停状态, 连续被拦截时暂停时间翻倍.
+type coolDown struct {
+	base time.Duration
+	max  time.Duration
+
+	mutex sync.Mutex
+	level uint
+	until time.Time
+}
+
+// trip 在没有暂停时开始一次新的暂停, 返回暂停的剩余时长.
+// 暂停期间被拦截的并发请求不会再次升级暂停时间, 此时 started 为 false.
+func (cd *coolDown) trip() (dur time.Duration, started bool) {
+	cd.mutex.Lock()
+	defer cd.mutex.Unlock()
+
+	if remain := time.Until(cd.until); remain > 0 {
+		return remain, false
+	}
+
+	dur = cd.base
+	for i := uint(0); i < cd.level && dur < cd.max; i++ {
+		dur *= 2
+	}
+	if dur > cd.max {
+		dur = cd.max
+	}
+	cd.level++
+	cd.until = time.Now().Add(dur)
+	return dur, true
+}
+
+// reset 在请求正常后清除升级的暂停时间
+func (cd *coolDown) reset() {
+	cd.mutex.Lock()
+	defer cd.mutex.Unlock()
+
+	cd.level = 0
+}
+
+// wait 等待暂停结束, ctx 取消时返回 ctx.Err()
+func (cd *coolDown) wait(ctx context.Context) error {
+	for {
+		cd.mutex.Lock()
+		dur := time.Until(cd.until)
+		cd.mutex.Unlock()
+
+		if dur <= 0 {
+			return ctx.Err()
+		}
+
+		timer := time.NewTimer(dur)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package modules
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/astaxie/beego/logs"
+)
+
+type PagingHotList struct {
+	Paging *Paging        `json:"paging"`
+	Data   []*HotListFeed `json:"data"`
+}
+
+type HotListFeed struct {
+	Target *FeedTarget `json:"target"`
+	// DetailText 是热度, 例如 "1234 万热度"
+	DetailText string `json:"detail_text"`
+}
+
+// parseHeat 把 "1234 万热度", "1.2 亿热度" 转换为数字, 无法解析时返回 0
+func parseHeat(text string) uint64 {
+	text = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "热度"))
+
+	unit := 1.0
+	if strings.HasSuffix(text, "万") {
+		unit = 1e4
+		text = strings.TrimSuffix(text, "万")
+	} else if strings.HasSuffix(text, "亿") {
+		unit = 1e8
+		text = strings.TrimSuffix(text, "亿")
+	}
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	if err != nil || value < 0 {
+		return 0
+	}
+	return uint64(value*unit + 0.5)
+}
+
+// parseHotList 解析一页热榜, rank 是这一页第一条的排名; 只保留问题, 但其他内容也占用排名
+func parseHotList(data []byte, snapshotTime time.Time, rank int) (*PagingHotList, []*HotListTable, error) {
+	ph := &PagingHotList{}
+	if err := json.Unmarshal(data, ph); err != nil {
+		return nil, nil, err
+	}
+
+	var hotList []*HotListTable
+	for i, feed := range ph.Data {
+		if feed.Target == nil || feed.Target.Type != "question" {
+			continue
+		}
+		hotList = append(hotList, &HotListTable{
+			SnapshotTime: snapshotTime,
+			Rank:         rank + i,
+			QuestionID:   strconv.FormatUint(feed.Target.ID, 10),
+			Title:        feed.Target.Title,
+			Heat:         parseHeat(feed.DetailText),
+		})
+	}
+	return ph, hotList, nil
+}
+
+// CollectHotList 保存一次热榜快照, 需要配合 Schedule 定期运行; 热榜中的问题也会加入 question 表.
+// 所有页都请求成功后才一次性保存, 中断或出错时丢弃这次快照.
+func (zh *ZhiHu) CollectHotList(ctx context.Context, job *CollectJob) {
+	snapshotTime := time.Now()
+	rank := 1
+
+	var hotList []*HotListTable
+	var questions []*Question
+	handle := func(ctx context.Context, data []byte) (*Paging, int, error) {
+		ph, items, err := parseHotList(data, snapshotTime, rank)
+		if err != nil {
+			return nil, 0, err
+		}
+		rank += len(ph.Data)
+
+		for _, ht := range items {
+			questions = append(questions, &Question{QuestionID: ht.QuestionID, Title: ht.Title})
+		}
+		hotList = append(hotList, items...)
+		return ph.Paging, len(ph.Data), nil
+	}
+
+	// 快照不需要保存进度, 中断后下次重新请求
+	if _, err := zh.continuePaging(ctx, job, zh.api.HotListURL(), job.config.MaxPages, handle); ctx.Err() != nil {
+		logs.Info("stop collect hot list: %s", err)
+		return
+	} else if err != nil {
+		logs.Error("error when collect hot list: %s", err)
+		return
+	}
+
+	if err := zh.dataSource.InsertQuestions(ctx, questions); err != nil {
+		logs.Error("error when insert questions of hot list: %s", err)
+		return
+	}
+	if err := zh.dataSource.InsertHotList(ctx, hotList); err != nil {
+		logs.Error("error when insert hot list: %s", err)
+		return
+	}
+	logs.Info("collect hot list success, items: %d", len(hotList))
+}
@@ -0,0 +1,525 @@
+package modules
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/astaxie/beego/logs"
+)
+
+const progressLogInterval = 10 * time.Minute
+
+// 展开 urlToken 的方向
+const (
+	directionBoth     = "both"
+	directionFollowee = "followee"
+	directionFollower = "follower"
+)
+
+// jobConfigs 返回要运行的任务, 没有配置 jobs 时使用 mode 作为唯一的任务.
+func jobConfigs(config *ZhiHuConfig) ([]*JobConfig, error) {
+	jobs := config.Jobs
+	if len(jobs) == 0 {
+		jobs = []*JobConfig{{Mode: config.Mode}}
+	}
+
+	names := make(map[string]bool)
+	modes := make(map[int]string)
+	for _, job := range jobs {
+		if job.Name == "" {
+			job.Name = fmt.Sprintf("mode-%d", job.Mode)
+		}
+		if names[job.Name] {
+			return nil, fmt.Errorf("duplicate job name: %s", job.Name)
+		}
+		names[job.Name] = true
+
+		if !validMode(job.Mode) {
+			return nil, fmt.Errorf("unexpected mode: %d, job: %s", job.Mode, job.Name)
+		}
+		// 同一种模式的进度保存在同一张表中, 不能同时运行
+		for _, mode := range progressModes(job.Mode) {
+			if name, ok := modes[mode]; ok {
+				return nil, fmt.Errorf("job %s and %s use the same progress, mode: %d", name, job.Name, job.Mode)
+			}
+			modes[mode] = job.Name
+		}
+	}
+	return jobs, nil
+}
+
+func newJob(config *JobConfig) (*CollectJob, error) {
+	job := &CollectJob{
+		config: config,
+		done:   make(chan struct{}),
+	}
+
+	if config.Schedule != "" {
+		sched, err := parseSchedule(config.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("job %s: %w", config.Name, err)
+		}
+		job.schedule = sched
+	}
+
+	refreshAge, err := parseDuration(config.RefreshAge, defaultRefreshAge)
+	if err != nil {
+		return nil, fmt.Errorf("job %s: %w", config.Name, err)
+	}
+	job.refreshAge = refreshAge
+
+	if job.maxDuration, err = parseDuration(config.MaxDuration, 0); err != nil {
+		return nil, fmt.Errorf("job %s: %w", config.Name, err)
+	}
+	if config.MaxDepth < 0 {
+		return nil, fmt.Errorf("job %s: invalid max depth: %d", config.Name, config.MaxDepth)
+	}
+
+	switch config.Direction {
+	case "":
+		config.Direction = directionBoth
+	case directionBoth, directionFollowee, directionFollower:
+	default:
+		return nil, fmt.Errorf("job %s: unexpected direction: %s", config.Name, config.Direction)
+	}
+	if config.MaxFolloweePages < 0 || config.MaxFollowerPages < 0 || config.MaxPages < 0 {
+		return nil, fmt.Errorf("job %s: invalid max pages", config.Name)
+	}
+	if len(config.TopicFeeds) == 0 {
+		config.TopicFeeds = []string{topicFeedTop, topicFeedEssence, topicFeedTimeline}
+	}
+	for _, feed := range config.TopicFeeds {
+		switch feed {
+		case topicFeedTop, topicFeedEssence, topicFeedTimeline:
+		default:
+			return nil, fmt.Errorf("job %s: unexpected topic feed: %s", config.Name, feed)
+		}
+	}
+	if len(config.SearchTypes) == 0 {
+		config.SearchTypes = []string{searchPeople, searchTopic, searchQuestion}
+	}
+	for _, searchType := range config.SearchTypes {
+		switch searchType {
+		case searchPeople, searchTopic, searchQuestion:
+		default:
+			return nil, fmt.Errorf("job %s: unexpected search type: %s", config.Name, searchType)
+		}
+	}
+
+	switch config.Missed {
+	case "":
+		config.Missed = missedSkip
+	case missedSkip, missedRun:
+	default:
+		return nil, fmt.Errorf("job %s: unexpected missed policy: %s", config.Name, config.Missed)
+	}
+	return job, nil
+}
+
+// CollectJob 是一个独立运行的采集任务, 共享 ZhiHu 的 client, limiter 和数据库.
+type CollectJob struct {
+	config *JobConfig
+	// schedule 为 nil 时只运行一次
+	schedule schedule
+	// refreshPeople 模式中用户信息的有效期
+	refreshAge time.Duration
+	// 每次运行的最长时间, 0 表示不限制
+	maxDuration time.Duration
+
+	// 以下计数使用 atomic 访问
+	pages uint64
+	items uint64
+	// profiles 是流水线模式中采集的用户信息数量, 不计入 pages
+	profiles uint64
+	// nodes 是本次运行新插入的 urlToken 数量, 用于判断 MaxNodes
+	nodes uint64
+
+	mutex     sync.Mutex
+	runs      int
+	startTime time.Time
+	endTime   time.Time
+	nextRun   time.Time
+
+	// 流水线模式下新发现的 urlToken 会放入 queue, 其他模式为 nil
+	queue chan *URLToken
+
+	// cancel 在 Start 时设置, 调用 Stop 后任务不再运行
+	cancel  context.CancelFunc
+	stopped bool
+	done    chan struct{}
+}
+
+// JobProgress 是任务进度的快照
+type JobProgress struct {
+	Name    string
+	Mode    int
+	Running bool
+	Stopped bool
+	// Runs 已开始的运行次数, StartTime 和 EndTime 是最近一次运行的时间
+	Runs      int
+	StartTime time.Time
+	EndTime   time.Time
+	// NextRun 是下一次运行的时间, 没有安排时为零值
+	NextRun time.Time
+	// Pages 已请求的页面数, Items 已保存的数据条数
+	Pages uint64
+	Items uint64
+	// Profiles 流水线模式中已采集的用户信息数量
+	Profiles uint64
+}
+
+func (jp *JobProgress) String() string {
+	cost := jp.EndTime.Sub(jp.StartTime)
+	if jp.Running {
+		cost = time.Since(jp.StartTime)
+	}
+	str := fmt.Sprintf("job: %s, mode: %d, running: %t, stopped: %t, runs: %d, pages: %d, items: %d, cost: %s",
+		jp.Name, jp.Mode, jp.Running, jp.Stopped, jp.Runs, jp.Pages, jp.Items, cost)
+	if jp.Profiles != 0 {
+		str += fmt.Sprintf(", profiles: %d", jp.Profiles)
+	}
+	if !jp.NextRun.IsZero() {
+		str += fmt.Sprintf(", next run: %s", jp.NextRun.Format("2006-01-02 15:04:05"))
+	}
+	return str
+}
+
+func (job *CollectJob) Name() string {
+	return job.config.Name
+}
+
+// addPage 记录一次请求和其中保存的数据条数, job 可以为 nil
+func (job *CollectJob) addPage(items int) {
+	if job == nil {
+		return
+	}
+	atomic.AddUint64(&job.pages, 1)
+	atomic.AddUint64(&job.items, uint64(items))
+}
+
+// addProfile 记录流水线模式中采集的一个用户信息
+func (job *CollectJob) addProfile() {
+	atomic.AddUint64(&job.profiles, 1)
+}
+
+// setNodes 设置本次运行新插入的 urlToken 数量, job 可以为 nil
+func (job *CollectJob) setNodes(nodes uint64) {
+	if job == nil {
+		return
+	}
+	atomic.StoreUint64(&job.nodes, nodes)
+}
+
+// allowNodes 返回还可以插入的 urlToken 数量, 超过 MaxNodes 的部分不会插入
+func (job *CollectJob) allowNodes(count int) int {
+	if job == nil || job.config.MaxNodes == 0 {
+		return count
+	}
+
+	nodes := atomic.LoadUint64(&job.nodes)
+	if nodes >= job.config.MaxNodes {
+		return 0
+	}
+	if left := job.config.MaxNodes - nodes; uint64(count) > left {
+		return int(left)
+	}
+	return count
+}
+
+func (job *CollectJob) addNodes(count int) {
+	if job == nil {
+		return
+	}
+	atomic.AddUint64(&job.nodes, uint64(count))
+}
+
+// score 返回新发现的 urlToken 的分数, 没有配置 priority 时为 0
+func (job *CollectJob) score(follow *Followee, depth int) float64 {
+	if job == nil || job.config.Priority == nil {
+		return 0
+	}
+
+	pc := job.config.Priority
+	score := pc.FollowerWeight*math.Log10(1+float64(follow.FollowerCount)) +
+		pc.AnswerWeight*math.Log10(1+float64(follow.AnswerCount)) -
+		pc.DepthWeight*float64(depth)
+	if follow.IsOrg {
+		score -= pc.OrgPenalty
+	}
+	return score
+}
+
+// reachMaxDepth 判断深度为 depth 的 urlToken 是否不再展开
+func (job *CollectJob) reachMaxDepth(depth int) bool {
+	if job == nil || job.config.MaxDepth == 0 || depth < job.config.MaxDepth {
+		return false
+	}
+	logs.Info("job %s reach max depth: %d", job.Name(), job.config.MaxDepth)
+	return true
+}
+
+func (job *CollectJob) reachMaxNodes() bool {
+	if job.allowNodes(1) != 0 {
+		return false
+	}
+	logs.Info("job %s reach max nodes: %d", job.Name(), job.config.MaxNodes)
+	return true
+}
+
+func (job *CollectJob) Progress() *JobProgress {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+
+	jp := &JobProgress{
+		Name:      job.config.Name,
+		Mode:      job.config.Mode,
+		Running:   !job.startTime.IsZero() && job.endTime.IsZero(),
+		Stopped:   job.stopped,
+		Runs:      job.runs,
+		StartTime: job.startTime,
+		EndTime:   job.endTime,
+		NextRun:   job.nextRun,
+		Pages:     atomic.LoadUint64(&job.pages),
+		Items:     atomic.LoadUint64(&job.items),
+		Profiles:  atomic.LoadUint64(&job.profiles),
+	}
+	return jp
+}
+
+func (job *CollectJob) Done() <-chan struct{} {
+	return job.done
+}
+
+// withCancel 返回任务自己的 ctx, 调用 Stop 时只取消这个任务
+func (job *CollectJob) withCancel(parent context.Context) context.Context {
+	ctx, cancel := context.WithCancel(parent)
+
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	job.cancel = cancel
+	if job.stopped {
+		cancel()
+	}
+	return ctx
+}
+
+// Stop 取消任务, 正在运行时会保存进度后结束, 之后不再调度
+func (job *CollectJob) Stop() {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+
+	job.stopped = true
+	if job.cancel != nil {
+		job.cancel()
+	}
+}
+
+// started 返回任务是否已经由 Start 启动
+func (job *CollectJob) started() bool {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	return job.cancel != nil
+}
+
+// scheduleJob 按 schedule 重复运行任务, 同一个任务不会同时运行多次
+func (zh *ZhiHu) scheduleJob(ctx context.Context, job *CollectJob) {
+	defer close(job.done)
+
+	if job.schedule == nil {
+		zh.runJob(ctx, job)
+		return
+	}
+
+	next := job.schedule.next(time.Now())
+	if last, err := zh.dataSource.GetLastJobRun(ctx, job.Name()); err == nil {
+		// 根据上次运行的时间判断进程停止期间是否错过了运行
+		next = zh.checkMissed(job, job.schedule.next(last.StartTime))
+	} else if err != sql.ErrNoRows {
+		logs.Error("error when get last run of job %s: %s", job.Name(), err)
+	}
+
+	for {
+		job.mutex.Lock()
+		job.nextRun = next
+		job.mutex.Unlock()
+
+		if !next.IsZero() {
+			logs.Info("job %s next run at %s", job.Name(), next.Format("2006-01-02 15:04:05"))
+			timer := time.NewTimer(time.Until(next))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+
+		start := time.Now()
+		zh.runJob(ctx, job)
+		if ctx.Err() != nil {
+			return
+		}
+		// 运行时间超过调度间隔时, 运行期间的调度都被错过
+		next = zh.checkMissed(job, job.schedule.next(start))
+		if next.IsZero() {
+			// 立即运行时也暂停 pauseDuration, 避免没有新数据时空转
+			next = time.Now().Add(zh.pauseDuration)
+		}
+	}
+}
+
+// checkMissed 在 next 已经过去时按照 missed 策略返回新的运行时间
+func (zh *ZhiHu) checkMissed(job *CollectJob, next time.Time) time.Time {
+	now := time.Now()
+	if next.IsZero() || next.After(now) {
+		return next
+	}
+
+	if job.config.Missed == missedRun {
+		logs.Warn("job %s missed run at %s, run now", job.Name(), next.Format("2006-01-02 15:04:05"))
+		return time.Time{}
+	}
+	logs.Warn("job %s missed run at %s, skip", job.Name(), next.Format("2006-01-02 15:04:05"))
+	return job.schedule.next(now)
+}
+
+// runJob 运行一次任务直到结束或 ctx 取消
+func (zh *ZhiHu) runJob(ctx context.Context, job *CollectJob) {
+	jr := &JobRun{
+		Name:      job.Name(),
+		StartTime: time.Now(),
+	}
+
+	job.mutex.Lock()
+	job.runs++
+	job.startTime = jr.StartTime
+	job.endTime = time.Time{}
+	job.nextRun = time.Time{}
+	job.mutex.Unlock()
+	logs.Info("job %s start, mode: %d", job.Name(), job.config.Mode)
+
+	// 超时和取消一样会保存进度, 但不影响之后的调度
+	parent := ctx
+	if job.maxDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, job.maxDuration)
+		defer cancel()
+	}
+
+	switch job.config.Mode {
+	case collectURLToken:
+		zh.CollectURLToken(ctx, job)
+	case collectTopicID:
+		zh.CollectTopicID(ctx, job)
+	case collectTopic:
+		zh.CollectTopic(ctx, job)
+	case collectPeople:
+		zh.CollectPeople(ctx, job)
+	case collectPipeline:
+		zh.CollectPipeline(ctx, job)
+	case refreshPeople:
+		zh.RefreshPeople(ctx, job)
+	case collectQuestionAnswers:
+		zh.CollectQuestionAnswers(ctx, job)
+	case collectUserAnswers:
+		zh.CollectUserAnswers(ctx, job)
+	case collectUserArticles:
+		zh.CollectUserArticles(ctx, job)
+	case collectColumnArticles:
+		zh.CollectColumnArticles(ctx, job)
+	case collectTopicQuestions:
+		zh.CollectTopicQuestions(ctx, job)
+	case collectComments:
+		zh.CollectComments(ctx, job)
+	case collectHotList:
+		zh.CollectHotList(ctx, job)
+	case collectSearch:
+		zh.CollectSearch(ctx, job)
+	}
+
+	if parent.Err() == nil && ctx.Err() != nil {
+		logs.Info("job %s reach max duration: %s", job.Name(), job.maxDuration)
+	}
+
+	jr.EndTime = time.Now()
+	job.mutex.Lock()
+	job.endTime = jr.EndTime
+	job.mutex.Unlock()
+	logs.Info("job finish, %s", job.Progress())
+
+	if job.schedule == nil {
+		return
+	}
+	// ctx 取消后依然需要保存运行记录
+	if err := zh.dataSource.InsertJobRun(context.Background(), jr); err != nil {
+		logs.Error("error when insert run of job %s: %s", job.Name(), err)
+	}
+}
+
+// progressModes 返回和 mode 共用进度的模式
+func progressModes(mode int) []int {
+	if mode == collectPipeline {
+		// 流水线的发现部分和 collectURLToken 共用 urlTokenProgress, 采集部分和 collectPeople 共用 peopleProgress
+		return []int{collectURLToken, collectPeople}
+	}
+	return []int{mode}
+}
+
+func validMode(mode int) bool {
+	switch mode {
+	case collectURLToken, collectTopicID, collectTopic, collectPeople, collectPipeline, refreshPeople,
+		collectQuestionAnswers, collectUserAnswers, collectUserArticles, collectColumnArticles, collectTopicQuestions,
+		collectComments, collectHotList, collectSearch:
+		return true
+	}
+	return false
+}
+
+// StopJob 停止名为 name 的任务并等待其结束, 其他任务继续运行
+func (zh *ZhiHu) StopJob(name string) error {
+	for _, job := range zh.jobs {
+		if job.Name() != name {
+			continue
+		}
+		job.Stop()
+		if job.started() {
+			<-job.Done()
+		}
+		logs.Info("job %s stopped", name)
+		return nil
+	}
+	return fmt.Errorf("job not found: %s", name)
+}
+
+// Progress 返回所有任务的进度
+func (zh *ZhiHu) Progress() []*JobProgress {
+	var progress []*JobProgress
+	for _, job := range zh.jobs {
+		progress = append(progress, job.Progress())
+	}
+	return progress
+}
+
+func (zh *ZhiHu) logProgressRegularly(ctx context.Context) {
+	ticker := time.NewTicker(progressLogInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-zh.stopFinish:
+			return
+		case <-ticker.C:
+		}
+
+		for _, jp := range zh.Progress() {
+			logs.Info("%s", jp)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package modules
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+func newRateLimiter(interval time.Duration) *rateLimiter {
+	return &rateLimiter{
+		interval: interval,
+	}
+}
+
+// rateLimiter 保证所有采集器发出的请求之间至少间隔 interval
+type rateLimiter struct {
+	mutex    sync.Mutex
+	interval time.Duration
+	last     time.Time
+}
+
+// raise 在 interval 更大时更新间隔, 用于 robots.txt 的 Crawl-delay
+func (rl *rateLimiter) raise(interval time.Duration) {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	if interval > rl.interval {
+		rl.interval = interval
+	}
+}
+
+// wait 预约下一个可以请求的时间并等待, ctx 取消时返回 ctx.Err()
+func (rl *rateLimiter) wait(ctx context.Context) error {
+	rl.mutex.Lock()
+	now := time.Now()
+	next := rl.last.Add(rl.interval)
+	if next.Before(now) {
+		next = now
+	}
+	rl.last = next
+	rl.mutex.Unlock()
+
+	dur := time.Until(next)
+	if dur <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(dur)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,948 @@
+package modules
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"sync"
+	"time"
+)
+
+// memStore 是 Store 的内存实现, 按 MySQL 的唯一索引和更新规则模拟各个表, 用于测试采集模式
+type memStore struct {
+	mutex sync.Mutex
+
+	urlTokens []*URLToken
+	// urlTokenGap 是每次插入前跳过的 id 数, 模拟自增 id 因插入失败等原因不连续
+	urlTokenGap      uint64
+	lastURLTokenID   uint64
+	expanded         map[uint64]bool
+	urlTokenProgress []*URLTokenProgress
+	priorityProgress []*URLTokenProgress
+
+	topicsID        []*TopicID
+	topicIDProgress []*TopicIDProgress
+	topics          map[uint64]*TopicTable
+	topicProgress   []*TopicProgress
+	topicIDRoots    []*TopicIDRoot
+	rootExpanded    map[uint64]bool
+
+	industries     map[string]uint64
+	people         map[uint64]*People
+	crawlStates    map[uint64]*crawlState
+	peopleProgress []*PeopleProgress
+	jobRuns        []*JobRun
+
+	questions             []*Question
+	topicQuestions        []*TopicQuestion
+	topicQuestionProgress []*TopicQuestionProgress
+
+	answers            []*AnswerTable
+	answerProgress     []*AnswerProgress
+	userAnswerProgress []*UserAnswerProgress
+
+	articles              []*ArticleTable
+	columns               []*ColumnTable
+	userArticleProgress   []*UserArticleProgress
+	columnArticleProgress []*ColumnArticleProgress
+
+	comments        []*CommentTable
+	commentProgress []*CommentProgress
+
+	hotList        []*HotListTable
+	searchProgress []*SearchProgress
+}
+
+var _ Store = (*memStore)(nil)
+
+func newMemStore() *memStore {
+	return &memStore{
+		expanded:     make(map[uint64]bool),
+		topics:       make(map[uint64]*TopicTable),
+		rootExpanded: make(map[uint64]bool),
+		industries:   make(map[string]uint64),
+		people:       make(map[uint64]*People),
+		crawlStates:  make(map[uint64]*crawlState),
+	}
+}
+
+// crawlState 是 urlToken 表中记录的用户信息采集状态
+type crawlState struct {
+	crawlTime     time.Time
+	followerCount uint64
+	gone          bool
+	failTime      time.Time
+}
+
+func (ms *memStore) crawlState(urlTokenID uint64) *crawlState {
+	state, ok := ms.crawlStates[urlTokenID]
+	if !ok {
+		state = &crawlState{}
+		ms.crawlStates[urlTokenID] = state
+	}
+	return state
+}
+
+func (ms *memStore) Close() error {
+	return nil
+}
+
+func (ms *memStore) InsertURLTokens(ctx context.Context, urlTokens []*URLToken) ([]*URLToken, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	var inserted []*URLToken
+	for _, urlToken := range urlTokens {
+		if ms.findURLToken(urlToken.URLToken) != nil {
+			continue
+		}
+		ms.lastURLTokenID += ms.urlTokenGap + 1
+		urlToken.ID = ms.lastURLTokenID
+		ut := *urlToken
+		ms.urlTokens = append(ms.urlTokens, &ut)
+		inserted = append(inserted, urlToken)
+	}
+	return inserted, nil
+}
+
+func (ms *memStore) findURLToken(urlToken string) *URLToken {
+	for _, ut := range ms.urlTokens {
+		if ut.URLToken == urlToken {
+			return ut
+		}
+	}
+	return nil
+}
+
+// GetURLToken 和 DataSource 一样返回 id 大于 offset 的第一个 urlToken
+func (ms *memStore) GetURLToken(ctx context.Context, offset uint64) (*URLToken, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	for _, ut := range ms.urlTokens {
+		if ut.ID > offset {
+			urlToken := *ut
+			return &urlToken, nil
+		}
+	}
+	return &URLToken{}, sql.ErrNoRows
+}
+
+func (ms *memStore) GetURLTokenByID(ctx context.Context, id uint64) (*URLToken, bool, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	ut := ms.urlTokenByID(id)
+	if ut == nil {
+		return &URLToken{}, false, sql.ErrNoRows
+	}
+	urlToken := *ut
+	return &urlToken, ms.expanded[id], nil
+}
+
+func (ms *memStore) urlTokenByID(id uint64) *URLToken {
+	for _, ut := range ms.urlTokens {
+		if ut.ID == id {
+			return ut
+		}
+	}
+	return nil
+}
+
+func (ms *memStore) GetFrontier(ctx context.Context, maxDepth int) (*URLToken, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	var frontier *URLToken
+	for _, ut := range ms.urlTokens {
+		if ms.expanded[ut.ID] || (maxDepth != 0 && ut.Depth >= maxDepth) {
+			continue
+		}
+		if frontier == nil || ut.Score > frontier.Score {
+			frontier = ut
+		}
+	}
+	if frontier == nil {
+		return &URLToken{}, sql.ErrNoRows
+	}
+	urlToken := *frontier
+	return &urlToken, nil
+}
+
+func (ms *memStore) MarkURLTokenExpanded(ctx context.Context, id uint64) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	ms.expanded[id] = true
+	return nil
+}
+
+func (ms *memStore) GetPriorityProgress(ctx context.Context) (*URLTokenProgress, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	if len(ms.priorityProgress) == 0 {
+		return &URLTokenProgress{}, sql.ErrNoRows
+	}
+	utp := *ms.priorityProgress[len(ms.priorityProgress)-1]
+	return &utp, nil
+}
+
+func (ms *memStore) InsertPriorityProgress(ctx context.Context, utp *URLTokenProgress) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	progress := *utp
+	progress.ID = uint64(len(ms.priorityProgress) + 1)
+	ms.priorityProgress = append(ms.priorityProgress, &progress)
+	return nil
+}
+
+func (ms *memStore) GetURLTokenOffset(ctx context.Context, urlTokenID uint64) (uint64, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	var offset uint64
+	for _, ut := range ms.urlTokens {
+		if ut.ID < urlTokenID {
+			offset++
+		}
+	}
+	return offset, nil
+}
+
+func (ms *memStore) GetURLTokenProgress(ctx context.Context) (*URLTokenProgress, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	if len(ms.urlTokenProgress) == 0 {
+		return &URLTokenProgress{}, sql.ErrNoRows
+	}
+	utp := *ms.urlTokenProgress[len(ms.urlTokenProgress)-1]
+	return &utp, nil
+}
+
+func (ms *memStore) InsertURLTokenProgress(ctx context.Context, utp *URLTokenProgress) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	progress := *utp
+	progress.ID = uint64(len(ms.urlTokenProgress) + 1)
+	ms.urlTokenProgress = append(ms.urlTokenProgress, &progress)
+	return nil
+}
+
+func (ms *memStore) CountURLToken(ctx context.Context) (uint64, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	return uint64(len(ms.urlTokens)), nil
+}
+
+func (ms *memStore) InsertTopicsID(ctx context.Context, topicsID []*TopicID) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+loop:
+	for _, topicID := range topicsID {
+		for _, ti := range ms.topicsID {
+			if ti.TopicID == topicID.TopicID {
+				continue loop
+			}
+		}
+		ti := *topicID
+		ti.ID = uint64(len(ms.topicsID) + 1)
+		ms.topicsID = append(ms.topicsID, &ti)
+	}
+	return nil
+}
+
+func (ms *memStore) GetTopicID(ctx context.Context, offset uint64) (*TopicID, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	if offset >= uint64(len(ms.topicsID)) {
+		return &TopicID{}, sql.ErrNoRows
+	}
+	ti := *ms.topicsID[offset]
+	return &ti, nil
+}
+
+func (ms *memStore) GetTopicIDOffset(ctx context.Context, topicID uint64) (uint64, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	var offset uint64
+	for _, ti := range ms.topicsID {
+		if ti.ID < topicID {
+			offset++
+		}
+	}
+	return offset, nil
+}
+
+func (ms *memStore) GetTopicIDProgress(ctx context.Context) (*TopicIDProgress, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	if len(ms.topicIDProgress) == 0 {
+		return &TopicIDProgress{}, sql.ErrNoRows
+	}
+	tip := *ms.topicIDProgress[len(ms.topicIDProgress)-1]
+	return &tip, nil
+}
+
+func (ms *memStore) InsertTopicIDProgress(ctx context.Context, tip *TopicIDProgress) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	progress := *tip
+	progress.ID = uint64(len(ms.topicIDProgress) + 1)
+	ms.topicIDProgress = append(ms.topicIDProgress, &progress)
+	return nil
+}
+
+func (ms *memStore) InsertTopic(ctx context.Context, tt *TopicTable) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	if topic, ok := ms.topics[tt.TopicID]; ok {
+		topic.FollowerCount = tt.FollowerCount
+		topic.QuestionCount = tt.QuestionCount
+		if tt.Name != "" {
+			topic.Name = tt.Name
+		}
+		if tt.Introduction != "" {
+			topic.Introduction = tt.Introduction
+		}
+		if tt.AvatarURL != "" {
+			topic.AvatarURL = tt.AvatarURL
+		}
+		if tt.BestAnswerersCount != 0 {
+			topic.BestAnswerersCount = tt.BestAnswerersCount
+		}
+		return nil
+	}
+	topic := *tt
+	topic.ID = uint64(len(ms.topics) + 1)
+	ms.topics[tt.TopicID] = &topic
+	return nil
+}
+
+func (ms *memStore) GetTopicProgress(ctx context.Context) (*TopicProgress, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	if len(ms.topicProgress) == 0 {
+		return &TopicProgress{}, sql.ErrNoRows
+	}
+	tp := *ms.topicProgress[len(ms.topicProgress)-1]
+	return &tp, nil
+}
+
+func (ms *memStore) InsertTopicProgress(ctx context.Context, tp *TopicProgress) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	progress := *tp
+	progress.ID = uint64(len(ms.topicProgress) + 1)
+	ms.topicProgress = append(ms.topicProgress, &progress)
+	return nil
+}
+
+func (ms *memStore) InsertTopicIDRoots(ctx context.Context, roots []*TopicIDRoot) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+loop:
+	for _, root := range roots {
+		for _, tir := range ms.topicIDRoots {
+			if tir.TopicID == root.TopicID && tir.RootTopicID == root.RootTopicID {
+				continue loop
+			}
+		}
+		tir := *root
+		tir.ID = uint64(len(ms.topicIDRoots) + 1)
+		ms.topicIDRoots = append(ms.topicIDRoots, &tir)
+	}
+	return nil
+}
+
+func (ms *memStore) GetTopicIDRootFrontier(ctx context.Context, rootTopicIDs []string, maxDepth int) (*TopicIDRoot, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	roots := make(map[string]bool)
+	for _, id := range rootTopicIDs {
+		roots[id] = true
+	}
+
+	var frontier *TopicIDRoot
+	for _, tir := range ms.topicIDRoots {
+		if ms.rootExpanded[tir.ID] || !roots[tir.RootTopicID] || (maxDepth != 0 && tir.Depth >= maxDepth) {
+			continue
+		}
+		if frontier == nil || tir.Depth < frontier.Depth {
+			frontier = tir
+		}
+	}
+	if frontier == nil {
+		return &TopicIDRoot{}, sql.ErrNoRows
+	}
+	tir := *frontier
+	return &tir, nil
+}
+
+func (ms *memStore) MarkTopicIDRootExpanded(ctx context.Context, id uint64) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	ms.rootExpanded[id] = true
+	return nil
+}
+
+func (ms *memStore) InsertIndustry(ctx context.Context, industry string) (uint64, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	if id, ok := ms.industries[industry]; ok {
+		return id, nil
+	}
+	id := uint64(len(ms.industries) + 1)
+	ms.industries[industry] = id
+	return id, nil
+}
+
+func (ms *memStore) InsertPeople(ctx context.Context, people *People) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	if people.CrawlTime.IsZero() {
+		people.CrawlTime = time.Now()
+	}
+	p := *people
+	ms.people[p.URLTokenID] = &p
+
+	state := ms.crawlState(p.URLTokenID)
+	state.crawlTime = p.CrawlTime
+	state.followerCount = p.FollowerCount
+	state.gone = false
+	state.failTime = time.Time{}
+	return nil
+}
+
+func (ms *memStore) MarkPeopleFailed(ctx context.Context, urlTokenID uint64, gone bool) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	state := ms.crawlState(urlTokenID)
+	state.gone = gone
+	state.failTime = time.Now()
+	return nil
+}
+
+func (ms *memStore) GetStalePeople(ctx context.Context, before, failedBefore time.Time, limit int) ([]*URLToken, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	var stale []*URLToken
+	for _, ut := range ms.urlTokens {
+		state := ms.crawlState(ut.ID)
+		if state.gone || (!state.crawlTime.IsZero() && !state.crawlTime.Before(before)) ||
+			(!state.failTime.IsZero() && !state.failTime.Before(failedBefore)) {
+			continue
+		}
+		urlToken := *ut
+		stale = append(stale, &urlToken)
+	}
+	sort.SliceStable(stale, func(i, j int) bool {
+		return ms.crawlState(stale[i].ID).followerCount > ms.crawlState(stale[j].ID).followerCount
+	})
+	if len(stale) > limit {
+		stale = stale[:limit]
+	}
+	return stale, nil
+}
+
+func (ms *memStore) GetPeopleProgress(ctx context.Context) (*PeopleProgress, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	if len(ms.peopleProgress) == 0 {
+		return &PeopleProgress{}, sql.ErrNoRows
+	}
+	pp := *ms.peopleProgress[len(ms.peopleProgress)-1]
+	return &pp, nil
+}
+
+func (ms *memStore) InsertPeopleProgress(ctx context.Context, pp *PeopleProgress) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	progress := *pp
+	progress.ID = uint64(len(ms.peopleProgress) + 1)
+	ms.peopleProgress = append(ms.peopleProgress, &progress)
+	return nil
+}
+
+func (ms *memStore) GetLastJobRun(ctx context.Context, name string) (*JobRun, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	for i := len(ms.jobRuns) - 1; i >= 0; i-- {
+		if ms.jobRuns[i].Name == name {
+			jr := *ms.jobRuns[i]
+			return &jr, nil
+		}
+	}
+	return &JobRun{}, sql.ErrNoRows
+}
+
+func (ms *memStore) InsertJobRun(ctx context.Context, jr *JobRun) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	run := *jr
+	run.ID = uint64(len(ms.jobRuns) + 1)
+	ms.jobRuns = append(ms.jobRuns, &run)
+	return nil
+}
+
+func (ms *memStore) InsertQuestions(ctx context.Context, questions []*Question) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+loop:
+	for _, question := range questions {
+		for _, q := range ms.questions {
+			if q.QuestionID != question.QuestionID {
+				continue
+			}
+			if question.Title != "" {
+				q.Title = question.Title
+			}
+			if question.AnswerCount != 0 {
+				q.AnswerCount = question.AnswerCount
+			}
+			if question.FollowerCount != 0 {
+				q.FollowerCount = question.FollowerCount
+			}
+			if !question.CreatedTime.IsZero() {
+				q.CreatedTime = question.CreatedTime
+			}
+			continue loop
+		}
+		q := *question
+		q.ID = uint64(len(ms.questions) + 1)
+		ms.questions = append(ms.questions, &q)
+	}
+	return nil
+}
+
+func (ms *memStore) GetQuestion(ctx context.Context, offset uint64) (*Question, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	if offset >= uint64(len(ms.questions)) {
+		return &Question{}, sql.ErrNoRows
+	}
+	q := *ms.questions[offset]
+	return &q, nil
+}
+
+func (ms *memStore) GetQuestionByQuestionID(ctx context.Context, questionID string) (*Question, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	for _, q := range ms.questions {
+		if q.QuestionID == questionID {
+			question := *q
+			return &question, nil
+		}
+	}
+	return &Question{}, sql.ErrNoRows
+}
+
+func (ms *memStore) GetQuestionOffset(ctx context.Context, id uint64) (uint64, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	var offset uint64
+	for _, q := range ms.questions {
+		if q.ID < id {
+			offset++
+		}
+	}
+	return offset, nil
+}
+
+func (ms *memStore) InsertTopicQuestions(ctx context.Context, topicQuestions []*TopicQuestion) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+loop:
+	for _, topicQuestion := range topicQuestions {
+		for _, tq := range ms.topicQuestions {
+			if tq.TopicID == topicQuestion.TopicID && tq.QuestionID == topicQuestion.QuestionID {
+				continue loop
+			}
+		}
+		tq := *topicQuestion
+		tq.ID = uint64(len(ms.topicQuestions) + 1)
+		ms.topicQuestions = append(ms.topicQuestions, &tq)
+	}
+	return nil
+}
+
+func (ms *memStore) GetTopicQuestionProgress(ctx context.Context) (*TopicQuestionProgress, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	if len(ms.topicQuestionProgress) == 0 {
+		return &TopicQuestionProgress{}, sql.ErrNoRows
+	}
+	tqp := *ms.topicQuestionProgress[len(ms.topicQuestionProgress)-1]
+	return &tqp, nil
+}
+
+func (ms *memStore) InsertTopicQuestionProgress(ctx context.Context, tqp *TopicQuestionProgress) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	progress := *tqp
+	progress.ID = uint64(len(ms.topicQuestionProgress) + 1)
+	ms.topicQuestionProgress = append(ms.topicQuestionProgress, &progress)
+	return nil
+}
+
+func (ms *memStore) InsertAnswers(ctx context.Context, answers []*AnswerTable) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+loop:
+	for _, answer := range answers {
+		for _, at := range ms.answers {
+			if at.AnswerID != answer.AnswerID {
+				continue
+			}
+			if answer.URLTokenID > at.URLTokenID {
+				at.URLTokenID = answer.URLTokenID
+			}
+			at.VoteupCount = answer.VoteupCount
+			at.CommentCount = answer.CommentCount
+			at.UpdatedTime = answer.UpdatedTime
+			if answer.Content != "" {
+				at.Content = answer.Content
+			}
+			continue loop
+		}
+		at := *answer
+		at.ID = uint64(len(ms.answers) + 1)
+		ms.answers = append(ms.answers, &at)
+	}
+	return nil
+}
+
+func (ms *memStore) GetAnswer(ctx context.Context, offset uint64) (*AnswerTable, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	if offset >= uint64(len(ms.answers)) {
+		return &AnswerTable{}, sql.ErrNoRows
+	}
+	at := *ms.answers[offset]
+	return &at, nil
+}
+
+func (ms *memStore) GetAnswerOffset(ctx context.Context, id uint64) (uint64, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	var offset uint64
+	for _, at := range ms.answers {
+		if at.ID < id {
+			offset++
+		}
+	}
+	return offset, nil
+}
+
+func (ms *memStore) GetAnswerProgress(ctx context.Context) (*AnswerProgress, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	if len(ms.answerProgress) == 0 {
+		return &AnswerProgress{}, sql.ErrNoRows
+	}
+	ap := *ms.answerProgress[len(ms.answerProgress)-1]
+	return &ap, nil
+}
+
+func (ms *memStore) InsertAnswerProgress(ctx context.Context, ap *AnswerProgress) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	progress := *ap
+	progress.ID = uint64(len(ms.answerProgress) + 1)
+	ms.answerProgress = append(ms.answerProgress, &progress)
+	return nil
+}
+
+func (ms *memStore) GetUserAnswerProgress(ctx context.Context) (*UserAnswerProgress, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	if len(ms.userAnswerProgress) == 0 {
+		return &UserAnswerProgress{}, sql.ErrNoRows
+	}
+	uap := *ms.userAnswerProgress[len(ms.userAnswerProgress)-1]
+	return &uap, nil
+}
+
+func (ms *memStore) InsertUserAnswerProgress(ctx context.Context, uap *UserAnswerProgress) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	progress := *uap
+	progress.ID = uint64(len(ms.userAnswerProgress) + 1)
+	ms.userAnswerProgress = append(ms.userAnswerProgress, &progress)
+	return nil
+}
+
+func (ms *memStore) InsertArticles(ctx context.Context, articles []*ArticleTable) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+loop:
+	for _, article := range articles {
+		for _, at := range ms.articles {
+			if at.ArticleID != article.ArticleID {
+				continue
+			}
+			at.Title = article.Title
+			if article.URLTokenID > at.URLTokenID {
+				at.URLTokenID = article.URLTokenID
+			}
+			at.VoteupCount = article.VoteupCount
+			at.CommentCount = article.CommentCount
+			at.UpdatedTime = article.UpdatedTime
+			continue loop
+		}
+		at := *article
+		at.ID = uint64(len(ms.articles) + 1)
+		ms.articles = append(ms.articles, &at)
+	}
+	return nil
+}
+
+func (ms *memStore) GetArticle(ctx context.Context, offset uint64) (*ArticleTable, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	if offset >= uint64(len(ms.articles)) {
+		return &ArticleTable{}, sql.ErrNoRows
+	}
+	at := *ms.articles[offset]
+	return &at, nil
+}
+
+func (ms *memStore) GetArticleOffset(ctx context.Context, id uint64) (uint64, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	var offset uint64
+	for _, at := range ms.articles {
+		if at.ID < id {
+			offset++
+		}
+	}
+	return offset, nil
+}
+
+func (ms *memStore) InsertColumns(ctx context.Context, columns []*ColumnTable) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+loop:
+	for _, column := range columns {
+		for _, ct := range ms.columns {
+			if ct.ColumnID != column.ColumnID {
+				continue
+			}
+			if column.Title != "" {
+				ct.Title = column.Title
+			}
+			if column.Intro != "" {
+				ct.Intro = column.Intro
+			}
+			if column.AuthorURLToken != "" {
+				ct.AuthorURLToken = column.AuthorURLToken
+			}
+			if column.ArticlesCount > ct.ArticlesCount {
+				ct.ArticlesCount = column.ArticlesCount
+			}
+			if column.Followers != 0 {
+				ct.Followers = column.Followers
+			}
+			continue loop
+		}
+		ct := *column
+		ct.ID = uint64(len(ms.columns) + 1)
+		ms.columns = append(ms.columns, &ct)
+	}
+	return nil
+}
+
+func (ms *memStore) GetColumn(ctx context.Context, offset uint64) (*ColumnTable, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	if offset >= uint64(len(ms.columns)) {
+		return &ColumnTable{}, sql.ErrNoRows
+	}
+	ct := *ms.columns[offset]
+	return &ct, nil
+}
+
+func (ms *memStore) GetColumnByColumnID(ctx context.Context, columnID string) (*ColumnTable, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	for _, ct := range ms.columns {
+		if ct.ColumnID == columnID {
+			column := *ct
+			return &column, nil
+		}
+	}
+	return &ColumnTable{}, sql.ErrNoRows
+}
+
+func (ms *memStore) GetColumnOffset(ctx context.Context, id uint64) (uint64, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	var offset uint64
+	for _, ct := range ms.columns {
+		if ct.ID < id {
+			offset++
+		}
+	}
+	return offset, nil
+}
+
+func (ms *memStore) GetUserArticleProgress(ctx context.Context) (*UserArticleProgress, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	if len(ms.userArticleProgress) == 0 {
+		return &UserArticleProgress{}, sql.ErrNoRows
+	}
+	uap := *ms.userArticleProgress[len(ms.userArticleProgress)-1]
+	return &uap, nil
+}
+
+func (ms *memStore) InsertUserArticleProgress(ctx context.Context, uap *UserArticleProgress) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	progress := *uap
+	progress.ID = uint64(len(ms.userArticleProgress) + 1)
+	ms.userArticleProgress = append(ms.userArticleProgress, &progress)
+	return nil
+}
+
+func (ms *memStore) GetColumnArticleProgress(ctx context.Context) (*ColumnArticleProgress, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	if len(ms.columnArticleProgress) == 0 {
+		return &ColumnArticleProgress{}, sql.ErrNoRows
+	}
+	cp := *ms.columnArticleProgress[len(ms.columnArticleProgress)-1]
+	return &cp, nil
+}
+
+func (ms *memStore) InsertColumnArticleProgress(ctx context.Context, cp *ColumnArticleProgress) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	progress := *cp
+	progress.ID = uint64(len(ms.columnArticleProgress) + 1)
+	ms.columnArticleProgress = append(ms.columnArticleProgress, &progress)
+	return nil
+}
+
+func (ms *memStore) InsertComments(ctx context.Context, comments []*CommentTable) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+loop:
+	for _, comment := range comments {
+		for _, ct := range ms.comments {
+			if ct.CommentID == comment.CommentID {
+				ct.LikeCount = comment.LikeCount
+				continue loop
+			}
+		}
+		ct := *comment
+		ct.ID = uint64(len(ms.comments) + 1)
+		ms.comments = append(ms.comments, &ct)
+	}
+	return nil
+}
+
+func (ms *memStore) GetCommentProgress(ctx context.Context, resourceType string) (*CommentProgress, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	for i := len(ms.commentProgress) - 1; i >= 0; i-- {
+		if ms.commentProgress[i].ResourceType == resourceType {
+			cp := *ms.commentProgress[i]
+			return &cp, nil
+		}
+	}
+	return &CommentProgress{}, sql.ErrNoRows
+}
+
+func (ms *memStore) InsertCommentProgress(ctx context.Context, cp *CommentProgress) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	progress := *cp
+	progress.ID = uint64(len(ms.commentProgress) + 1)
+	ms.commentProgress = append(ms.commentProgress, &progress)
+	return nil
+}
+
+func (ms *memStore) InsertHotList(ctx context.Context, hotList []*HotListTable) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	for _, hot := range hotList {
+		ht := *hot
+		ht.ID = uint64(len(ms.hotList) + 1)
+		ms.hotList = append(ms.hotList, &ht)
+	}
+	return nil
+}
+
+func (ms *memStore) GetSearchProgress(ctx context.Context) (*SearchProgress, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	if len(ms.searchProgress) == 0 {
+		return &SearchProgress{}, sql.ErrNoRows
+	}
+	sp := *ms.searchProgress[len(ms.searchProgress)-1]
+	return &sp, nil
+}
+
+func (ms *memStore) InsertSearchProgress(ctx context.Context, sp *SearchProgress) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	progress := *sp
+	progress.ID = uint64(len(ms.searchProgress) + 1)
+	ms.searchProgress = append(ms.searchProgress, &progress)
+	return nil
+}
@@ -1,10 +1,23 @@
 package modules
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+	"zhihu/fakezhihu"
 )
 
 const configPath = "/home/jdxj/workspace/zhihu/config.json"
@@ -40,7 +53,7 @@ func TestDataSource(t *testing.T) {
 	pp := &PeopleProgress{
 		URLTokenID: 4,
 	}
-	if err := ds.InsertPeopleProgress(pp); err != nil {
+	if err := ds.InsertPeopleProgress(context.Background(), pp); err != nil {
 		t.Fatalf("%s\n", err)
 	}
 
@@ -57,7 +70,7 @@ func TestNewZhiHu(t *testing.T) {
 		t.Fatalf("%s", err)
 	}
 
-	if err := zh.crawlPeople(3, "liaoxuefeng"); err != nil {
+	if err := zh.crawlPeople(context.Background(), 3, "liaoxuefeng"); err != nil {
 		t.Fatalf("%s", err)
 	}
 }
@@ -128,3 +141,1719 @@ func TestJsonParse(t *testing.T) {
 		fmt.Printf("topic: %+v\n", *topic)
 	}
 }
+
+func TestCacheTransport(t *testing.T) {
+	var hits int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if r.URL.Path == "/error" {
+			fmt.Fprint(w, `{"error":{"code":10003,"message":"error","name":"ERR"}}`)
+			return
+		}
+		fmt.Fprintf(w, "hello %s", r.URL.Path)
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "zhihu-cache")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := &CacheConfig{Dir: dir, TTL: "1h"}
+	ct, err := newCacheTransport(config, defaultMaxBodySize, http.DefaultTransport)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	client := &http.Client{Transport: ct}
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL + "/a")
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		data, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(data) != "hello /a" {
+			t.Fatalf("unexpected body: %s", data)
+		}
+	}
+	if hits != 1 {
+		t.Fatalf("expect 1 hit, got: %d", hits)
+	}
+
+	// 200 的错误信息不会被缓存
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL + "/error")
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		resp.Body.Close()
+	}
+	if hits != 3 {
+		t.Fatalf("expect error response not cached, hits: %d", hits)
+	}
+
+	config.Offline = true
+	ct, err = newCacheTransport(config, defaultMaxBodySize, http.DefaultTransport)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	client = &http.Client{Transport: ct}
+	if _, err := client.Get(server.URL + "/b"); err == nil {
+		t.Fatalf("expect cache miss error in offline mode")
+	}
+	if hits != 3 {
+		t.Fatalf("offline mode should not request server, hits: %d", hits)
+	}
+}
+
+func TestCacheBodyLimit(t *testing.T) {
+	var hits int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		fmt.Fprintf(w, "hello %s", r.URL.Path)
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "zhihu-cache")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// 超出限制的响应不缓存, 但完整地交给调用者
+	ct, err := newCacheTransport(&CacheConfig{Dir: dir}, 8, http.DefaultTransport)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	client := &http.Client{Transport: ct}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL + "/large")
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		data, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(data) != "hello /large" {
+			t.Fatalf("unexpected body: %s", data)
+		}
+	}
+	if hits != 2 {
+		t.Fatalf("expect large response not cached, hits: %d", hits)
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	ctx := context.Background()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/blocked" {
+			fmt.Fprint(w, "<html>verify</html>")
+			return
+		}
+		fmt.Fprintf(w, `{"paging":{"is_end":true,"next":"%s/blocked"},"data":[{"url_token":"a"}]}`,
+			"http://"+r.Host)
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "zhihu-fixture")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	recorder, err := NewRecordTransport(dir, http.DefaultTransport)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	zh := &ZhiHu{
+		pauseDuration: time.Millisecond,
+		coolDown:      newCoolDown(time.Millisecond, time.Millisecond),
+		maxBodySize:   defaultMaxBodySize,
+		limiter:       newRateLimiter(0),
+		client:        &http.Client{Transport: recorder},
+	}
+	pf, err := zh.getFolloweeOrFollower(ctx, server.URL+"/followees")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if _, err := zh.getFolloweeOrFollower(ctx, pf.Paging.Next); err == nil {
+		t.Fatalf("expect error when get blocked page")
+	}
+	server.Close()
+
+	replayer, err := NewReplayTransport(dir)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	zh.client = &http.Client{Transport: replayer}
+	pf, err = zh.getFolloweeOrFollower(ctx, server.URL+"/followees")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(pf.Data) != 1 || pf.Data[0].URLToken != "a" {
+		t.Fatalf("unexpected replay data: %+v", pf.Data)
+	}
+	if _, err := zh.getFolloweeOrFollower(ctx, pf.Paging.Next); err == nil {
+		t.Fatalf("expect error when replay blocked page")
+	}
+	if _, err := zh.getFolloweeOrFollower(ctx, pf.Paging.Next); err == nil {
+		t.Fatalf("expect error when fixtures are used up")
+	}
+}
+
+func newFakeZhiHu(config *fakezhihu.Config) (*ZhiHu, *fakezhihu.Server) {
+	server := fakezhihu.NewServer(config)
+	api := &APIConfig{BaseURL: server.URL}
+	api.setDefault()
+
+	client, err := newClient(&HTTPConfig{})
+	if err != nil {
+		panic(err)
+	}
+
+	zh := &ZhiHu{
+		config: &ZhiHuConfig{
+			OwnURLToken: server.URLToken(0),
+			RootTopicID: server.TopicID(0),
+		},
+		api:           api,
+		pauseDuration: time.Millisecond,
+		coolDown:      newCoolDown(time.Millisecond, 4*time.Millisecond),
+		maxBodySize:   defaultMaxBodySize,
+		limiter:       newRateLimiter(0),
+		client:        client,
+		dataSource:    newMemStore(),
+	}
+	return zh, server
+}
+
+func newTestJob(t *testing.T, config *JobConfig) *CollectJob {
+	job, err := newJob(config)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	return job
+}
+
+func TestFakeFolloweeAndFollower(t *testing.T) {
+	ctx := context.Background()
+	zh, server := newFakeZhiHu(&fakezhihu.Config{Users: 30, Follows: 7, PageSize: 3})
+	defer server.Close()
+
+	collect := func(startURL string) []string {
+		var urlTokens []string
+		for nextURL := startURL; ; {
+			pf, err := zh.getFolloweeOrFollower(ctx, nextURL)
+			if err != nil {
+				t.Fatalf("%s", err)
+			}
+			if len(pf.Data) == 0 {
+				return urlTokens
+			}
+			for _, follow := range pf.Data {
+				urlTokens = append(urlTokens, follow.URLToken)
+			}
+			nextURL = pf.Paging.Next
+		}
+	}
+
+	followees := collect(zh.api.FolloweeURL(server.URLToken(0)))
+	if fmt.Sprint(followees) != fmt.Sprint(server.Followees(0)) {
+		t.Fatalf("unexpected followees: %v, expect: %v", followees, server.Followees(0))
+	}
+	followers := collect(zh.api.FollowerURL(server.URLToken(1)))
+	if fmt.Sprint(followers) != fmt.Sprint(server.Followers(1)) {
+		t.Fatalf("unexpected followers: %v, expect: %v", followers, server.Followers(1))
+	}
+}
+
+func TestFakeTopic(t *testing.T) {
+	ctx := context.Background()
+	zh, server := newFakeZhiHu(&fakezhihu.Config{Topics: 20, Children: 4, PageSize: 3})
+	defer server.Close()
+
+	var children []string
+	for nextURL := zh.api.TopicIDURL(server.TopicID(1)); ; {
+		pt, err := zh.getTopicID(ctx, nextURL)
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if len(pt.Data) == 0 {
+			break
+		}
+		for _, topic := range pt.Data {
+			children = append(children, topic.ID)
+		}
+		nextURL = pt.Paging.Next
+	}
+	if fmt.Sprint(children) != fmt.Sprint(server.Children(1)) {
+		t.Fatalf("unexpected children: %v, expect: %v", children, server.Children(1))
+	}
+
+	tt, err := zh.fetchTopic(ctx, zh.api.TopicWebPageURL(server.TopicID(2)), 3)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	followerCount, questionCount := server.TopicCounts(2)
+	if tt.TopicID != 3 || tt.FollowerCount != uint64(followerCount) || tt.QuestionCount != uint64(questionCount) {
+		t.Fatalf("unexpected topic: %+v", tt)
+	}
+}
+
+func TestFakePeople(t *testing.T) {
+	ctx := context.Background()
+	zh, server := newFakeZhiHu(nil)
+	defer server.Close()
+
+	people, err := zh.fetchPeople(ctx, server.URLToken(3))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if people.Name != server.URLToken(3) || people.FollowerCount != uint64(len(server.Followers(3))) {
+		t.Fatalf("unexpected people: %+v", people)
+	}
+	if people.Business.Name == "" || len(people.Locations) == 0 {
+		t.Fatalf("unexpected people business or locations: %+v", people)
+	}
+}
+
+// userIndex 返回 fakezhihu 中 url token 对应的下标
+func userIndex(t *testing.T, urlToken string) int {
+	i, err := strconv.Atoi(strings.TrimPrefix(urlToken, "user-"))
+	if err != nil {
+		t.Fatalf("unexpected url token: %s", urlToken)
+	}
+	return i
+}
+
+func TestCollectURLToken(t *testing.T) {
+	ctx := context.Background()
+	zh, server := newFakeZhiHu(&fakezhihu.Config{Users: 30, Follows: 3, PageSize: 2})
+	defer server.Close()
+
+	// 按关注和粉丝计算每个用户到 OwnURLToken 的距离
+	depths := map[string]int{server.URLToken(0): 0}
+	for queue := []int{0}; len(queue) != 0; queue = queue[1:] {
+		i := queue[0]
+		for _, urlToken := range append(server.Followees(i), server.Followers(i)...) {
+			if _, ok := depths[urlToken]; !ok {
+				depths[urlToken] = depths[server.URLToken(i)] + 1
+				queue = append(queue, userIndex(t, urlToken))
+			}
+		}
+	}
+
+	zh.CollectURLToken(ctx, newTestJob(t, &JobConfig{Mode: collectURLToken}))
+	ds := zh.dataSource.(*memStore)
+	if len(ds.urlTokens) != len(depths) {
+		t.Fatalf("unexpected url tokens: %d, expect: %d", len(ds.urlTokens), len(depths))
+	}
+	for _, ut := range ds.urlTokens {
+		if depth, ok := depths[ut.URLToken]; !ok || ut.Depth != depth {
+			t.Fatalf("unexpected url token: %+v, expect depth: %d", ut, depth)
+		}
+	}
+	if len(ds.urlTokenProgress) != 1 {
+		t.Fatalf("unexpected urlTokenProgress: %d", len(ds.urlTokenProgress))
+	}
+
+	var nearby int
+	for _, depth := range depths {
+		if depth <= 1 {
+			nearby++
+		}
+	}
+	// existing 是运行前表中已有的其他 urlToken, 不计入 MaxNodes
+	tests := []struct {
+		config   *JobConfig
+		existing int
+		expect   int
+	}{
+		{&JobConfig{Mode: collectURLToken, MaxNodes: 10}, 0, 10},
+		{&JobConfig{Mode: collectURLToken, MaxNodes: 10}, 20, 30},
+		{&JobConfig{Mode: collectURLToken, MaxDepth: 1}, 0, nearby},
+	}
+	for _, test := range tests {
+		ds := newMemStore()
+		for i := 0; i < test.existing; i++ {
+			if _, err := ds.InsertURLTokens(ctx, []*URLToken{{URLToken: fmt.Sprintf("other-%d", i)}}); err != nil {
+				t.Fatalf("%s", err)
+			}
+		}
+		zh.dataSource = ds
+		zh.CollectURLToken(ctx, newTestJob(t, test.config))
+		if count := len(ds.urlTokens); count != test.expect {
+			t.Fatalf("unexpected url tokens: %d, expect: %d, config: %+v", count, test.expect, test.config)
+		}
+	}
+}
+
+func TestCollectURLTokenPriority(t *testing.T) {
+	ctx := context.Background()
+	zh, server := newFakeZhiHu(&fakezhihu.Config{Users: 30, Follows: 3, PageSize: 2})
+	defer server.Close()
+	ds := zh.dataSource.(*memStore)
+	priority := &PriorityConfig{FollowerWeight: 1}
+
+	// 中断时进度保存到单独的表
+	zh.CollectURLToken(ctx, newTestJob(t, &JobConfig{Mode: collectURLToken, MaxNodes: 5, Priority: priority}))
+	if len(ds.priorityProgress) != 1 || len(ds.urlTokenProgress) != 0 {
+		t.Fatalf("unexpected progress, priority: %d, fifo: %d", len(ds.priorityProgress), len(ds.urlTokenProgress))
+	}
+
+	zh.CollectURLToken(ctx, newTestJob(t, &JobConfig{Mode: collectURLToken, Priority: priority}))
+	for _, ut := range ds.urlTokens {
+		if !ds.expanded[ut.ID] {
+			t.Fatalf("expect url token expanded: %+v", ut)
+		}
+	}
+
+	// 按插入顺序展开时跳过已经展开的 urlToken
+	requests := server.Requests()
+	zh.CollectURLToken(ctx, newTestJob(t, &JobConfig{Mode: collectURLToken}))
+	if server.Requests() != requests || len(ds.urlTokenProgress) != 1 {
+		t.Fatalf("unexpected requests: %d, progress: %d", server.Requests()-requests, len(ds.urlTokenProgress))
+	}
+}
+
+func TestRefreshPeople(t *testing.T) {
+	ctx := context.Background()
+	zh, server := newFakeZhiHu(&fakezhihu.Config{Users: 10})
+	defer server.Close()
+
+	// user-100 不存在, 采集时返回 404
+	var urlTokens []*URLToken
+	for _, i := range []int{0, 1, 2, 100} {
+		urlTokens = append(urlTokens, &URLToken{URLToken: server.URLToken(i)})
+	}
+	ds := zh.dataSource.(*memStore)
+	if _, err := ds.InsertURLTokens(ctx, urlTokens); err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	job := newTestJob(t, &JobConfig{Mode: refreshPeople})
+	zh.RefreshPeople(ctx, job)
+	if len(ds.people) != 3 || job.Progress().Pages != 3 || !ds.crawlStates[4].gone {
+		t.Fatalf("unexpected people: %d, progress: %s", len(ds.people), job.Progress())
+	}
+
+	// 刚采集过的用户和已注销的用户不会再被刷新
+	job = newTestJob(t, &JobConfig{Mode: refreshPeople})
+	zh.RefreshPeople(ctx, job)
+	if requests := server.Requests(); job.Progress().Pages != 0 || requests != 4 {
+		t.Fatalf("unexpected refresh, requests: %d, progress: %s", requests, job.Progress())
+	}
+}
+
+// failingStore 的 InsertURLTokens 前 fails 次返回错误
+type failingStore struct {
+	*memStore
+	fails int
+}
+
+func (fs *failingStore) InsertURLTokens(ctx context.Context, urlTokens []*URLToken) ([]*URLToken, error) {
+	if fs.fails > 0 {
+		fs.fails--
+		return nil, fmt.Errorf("insert url tokens failed")
+	}
+	return fs.memStore.InsertURLTokens(ctx, urlTokens)
+}
+
+func TestFolloweeInsertError(t *testing.T) {
+	ctx := context.Background()
+	zh, server := newFakeZhiHu(&fakezhihu.Config{Users: 20, Follows: 5, PageSize: 2})
+	defer server.Close()
+
+	ds := &failingStore{memStore: newMemStore(), fails: 1}
+	zh.dataSource = ds
+	job := newTestJob(t, &JobConfig{Mode: collectURLToken})
+
+	// 插入失败时下次从同一页继续
+	startURL := zh.api.FolloweeURL(server.URLToken(0))
+	if nextURL, err := zh.continueGetFollowee(ctx, job, startURL, 1, 0); nextURL != startURL || err == nil {
+		t.Fatalf("unexpected next url: %s, expect: %s, err: %v", nextURL, startURL, err)
+	}
+	if _, err := zh.continueGetFollowee(ctx, job, startURL, 1, 0); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(ds.urlTokens) != len(server.Followees(0)) {
+		t.Fatalf("unexpected url tokens: %d, expect: %d", len(ds.urlTokens), len(server.Followees(0)))
+	}
+
+	// 展开出错的 urlToken 不会被标记, 下次从出错的页继续
+	ds = &failingStore{memStore: newMemStore()}
+	zh.dataSource = ds
+	inserted, err := ds.InsertURLTokens(ctx, []*URLToken{{URLToken: server.URLToken(0)}})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	ds.fails = 1
+	priority := &PriorityConfig{FollowerWeight: 1}
+	zh.collectURLTokenByPriority(ctx, newTestJob(t, &JobConfig{Mode: collectURLToken, Priority: priority}))
+	if ds.expanded[inserted[0].ID] {
+		t.Fatalf("expect url token not expanded after insert error")
+	}
+	utp, err := ds.GetPriorityProgress(ctx)
+	if err != nil || utp.URLTokenID != inserted[0].ID || utp.NextFolloweeURL != startURL {
+		t.Fatalf("unexpected progress: %+v, err: %v", utp, err)
+	}
+	zh.CollectURLToken(ctx, newTestJob(t, &JobConfig{Mode: collectURLToken, Priority: priority}))
+	if !ds.expanded[inserted[0].ID] {
+		t.Fatalf("expect url token expanded")
+	}
+}
+
+func TestCollectTopicID(t *testing.T) {
+	ctx := context.Background()
+	zh, server := newFakeZhiHu(&fakezhihu.Config{Topics: 20, Children: 3, PageSize: 2})
+	defer server.Close()
+
+	zh.CollectTopicID(ctx, newTestJob(t, &JobConfig{Mode: collectTopicID}))
+
+	ds := zh.dataSource.(*memStore)
+	if len(ds.topicsID) != 20 {
+		t.Fatalf("unexpected topic ids: %d", len(ds.topicsID))
+	}
+	if len(ds.topicIDProgress) != 1 {
+		t.Fatalf("unexpected topicIDProgress: %d", len(ds.topicIDProgress))
+	}
+}
+
+func TestCollectTopicSubtree(t *testing.T) {
+	ctx := context.Background()
+	zh, server := newFakeZhiHu(&fakezhihu.Config{Topics: 20, Children: 3, PageSize: 2})
+	defer server.Close()
+
+	// 不存在的根话题返回 404, 标记后跳过
+	config := &JobConfig{Mode: collectTopicID, RootTopicIDs: []string{server.TopicID(0), "1"}}
+	zh.collectTopicSubtree(ctx, newTestJob(t, config))
+	ds := zh.dataSource.(*memStore)
+	if len(ds.topicsID) != 21 {
+		t.Fatalf("unexpected topic ids: %d", len(ds.topicsID))
+	}
+	for _, tir := range ds.topicIDRoots {
+		if !ds.rootExpanded[tir.ID] {
+			t.Fatalf("expect topic expanded: %+v", tir)
+		}
+	}
+
+	// 其他错误不标记, 下次运行时重新展开
+	zh, server = newFakeZhiHu(&fakezhihu.Config{Topics: 20, ErrorRate: 1, ErrorStatus: http.StatusUnauthorized})
+	defer server.Close()
+	zh.collectTopicSubtree(ctx, newTestJob(t, &JobConfig{Mode: collectTopicID, RootTopicIDs: []string{server.TopicID(0)}}))
+	ds = zh.dataSource.(*memStore)
+	if len(ds.topicIDRoots) != 1 || ds.rootExpanded[ds.topicIDRoots[0].ID] {
+		t.Fatalf("unexpected topic roots: %d, expanded: %v", len(ds.topicIDRoots), ds.rootExpanded)
+	}
+}
+
+func TestCollectTopic(t *testing.T) {
+	ctx := context.Background()
+	for _, disableTopicAPI := range []bool{false, true} {
+		zh, server := newFakeZhiHu(&fakezhihu.Config{Topics: 5, DisableTopicAPI: disableTopicAPI})
+
+		var topicsID []*TopicID
+		for i := 0; i < 5; i++ {
+			topicsID = append(topicsID, &TopicID{TopicID: server.TopicID(i), Name: fmt.Sprintf("topic-%d", i)})
+		}
+		ds := zh.dataSource.(*memStore)
+		if err := ds.InsertTopicsID(ctx, topicsID); err != nil {
+			t.Fatalf("%s", err)
+		}
+		// 已存在的话题会被更新, 从页面采集时没有的字段保留原来的值
+		stale := &TopicTable{TopicID: 1, Name: "old", Introduction: "old intro"}
+		if err := ds.InsertTopic(ctx, stale); err != nil {
+			t.Fatalf("%s", err)
+		}
+
+		zh.CollectTopic(ctx, newTestJob(t, &JobConfig{Mode: collectTopic}))
+		server.Close()
+
+		if len(ds.topics) != 5 {
+			t.Fatalf("unexpected topics: %d, disableTopicAPI: %t", len(ds.topics), disableTopicAPI)
+		}
+		for i := 0; i < 5; i++ {
+			tt := ds.topics[uint64(i+1)]
+			followerCount, questionCount := server.TopicCounts(i)
+			if tt == nil || tt.FollowerCount != uint64(followerCount) || tt.QuestionCount != uint64(questionCount) ||
+				tt.Name != fmt.Sprintf("topic-%d", i) {
+				t.Fatalf("unexpected topic: %+v, disableTopicAPI: %t", tt, disableTopicAPI)
+			}
+		}
+		if intro := ds.topics[1].Introduction; disableTopicAPI && intro != "old intro" ||
+			!disableTopicAPI && intro == "old intro" {
+			t.Fatalf("unexpected introduction: %s, disableTopicAPI: %t", intro, disableTopicAPI)
+		}
+	}
+}
+
+func TestCollectPeople(t *testing.T) {
+	ctx := context.Background()
+	zh, server := newFakeZhiHu(&fakezhihu.Config{Users: 10})
+	defer server.Close()
+
+	var urlTokens []*URLToken
+	for i := 0; i < 5; i++ {
+		urlTokens = append(urlTokens, &URLToken{URLToken: server.URLToken(i)})
+	}
+	ds := zh.dataSource.(*memStore)
+	if _, err := ds.InsertURLTokens(ctx, urlTokens); err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	zh.CollectPeople(ctx, newTestJob(t, &JobConfig{Mode: collectPeople}))
+
+	if len(ds.people) != 5 {
+		t.Fatalf("unexpected people: %d", len(ds.people))
+	}
+	for i := 0; i < 5; i++ {
+		people := ds.people[uint64(i+1)]
+		if people == nil || people.Name != server.URLToken(i) || people.FollowerCount != uint64(len(server.Followers(i))) {
+			t.Fatalf("unexpected people: %+v", people)
+		}
+	}
+	if len(ds.peopleProgress) != 1 {
+		t.Fatalf("unexpected peopleProgress: %d", len(ds.peopleProgress))
+	}
+}
+
+func TestFakeBlocked(t *testing.T) {
+	zh, server := newFakeZhiHu(&fakezhihu.Config{ErrorRate: 1})
+	defer server.Close()
+
+	// 被拦截时重试同一个 url, 多次重试后返回错误
+	ctx := context.Background()
+	if _, err := zh.getFolloweeOrFollower(ctx, zh.api.FolloweeURL(server.URLToken(0))); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expect ErrRateLimited, got: %v", err)
+	}
+	if server.Requests() != maxBlockRetries+1 || zh.coolDown.level < 2 {
+		t.Fatalf("expect retry with escalating cool down, requests: %d, level: %d",
+			server.Requests(), zh.coolDown.level)
+	}
+
+	// ctx 取消时在暂停中返回
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	zh.coolDown.until = time.Now().Add(time.Hour)
+	if _, err := zh.getFolloweeOrFollower(ctx, zh.api.FolloweeURL(server.URLToken(0))); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expect context.DeadlineExceeded, got: %v", err)
+	}
+}
+
+func TestCoolDownTripOnce(t *testing.T) {
+	cd := newCoolDown(time.Hour, 4*time.Hour)
+
+	// 同一次暂停中的并发拦截只升级一次
+	var wg sync.WaitGroup
+	var started int32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, ok := cd.trip(); ok {
+				atomic.AddInt32(&started, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if started != 1 || cd.level != 1 {
+		t.Fatalf("expect trip once, started: %d, level: %d", started, cd.level)
+	}
+
+	// 暂停结束后再次被拦截时升级
+	cd.until = time.Now()
+	if dur, ok := cd.trip(); !ok || dur != 2*time.Hour || cd.level != 2 {
+		t.Fatalf("expect escalated cool down, dur: %s, started: %v, level: %d", dur, ok, cd.level)
+	}
+}
+
+func TestCheckResponse(t *testing.T) {
+	apiURL, _ := url.Parse("https://www.zhihu.com/api/v4/members/a/followees")
+	unhumanURL, _ := url.Parse("https://www.zhihu.com/account/unhuman?type=unhuman")
+
+	cases := []struct {
+		status int
+		reqURL *url.URL
+		data   string
+		kind   error
+	}{
+		{http.StatusOK, apiURL, `{"paging":{},"data":[]}`, nil},
+		{http.StatusTooManyRequests, apiURL, ``, ErrRateLimited},
+		{http.StatusForbidden, apiURL, `{}`, ErrRateLimited},
+		{http.StatusOK, unhumanURL, `<html></html>`, ErrRateLimited},
+		{http.StatusOK, apiURL, `{"error":{"code":40352,"message":"need verify"}}`, ErrRateLimited},
+		{http.StatusOK, apiURL, `{"error":{"code":100,"redirect":"https://www.zhihu.com/account/unhuman"}}`, ErrRateLimited},
+		{http.StatusOK, apiURL, `<html>请输入验证码</html>`, ErrRateLimited},
+		{http.StatusOK, apiURL, `{"data":[{"content":"请输入验证码"}]}`, nil},
+		{http.StatusOK, apiURL, `[{"excerpt":"unhuman"}]`, nil},
+		{http.StatusNotFound, apiURL, `{"error":{"code":404,"message":"not found","name":"NotFoundError"}}`, ErrGone},
+		{http.StatusUnauthorized, apiURL, `{"error":{"code":100,"message":"need login","name":"AuthenticationInvalidRequest"}}`, ErrNotLoggedIn},
+		{http.StatusBadGateway, apiURL, `<html>bad gateway</html>`, ErrServer},
+		{http.StatusOK, apiURL, `{"error":{"code":10003,"message":"bad request"}}`, ErrUnexpected},
+	}
+	for i, c := range cases {
+		resp := &http.Response{
+			StatusCode: c.status,
+			Request:    &http.Request{URL: c.reqURL},
+		}
+		err := checkResponse(resp, []byte(c.data))
+		if c.kind == nil {
+			if err != nil {
+				t.Fatalf("case %d: unexpected error: %s", i, err)
+			}
+			continue
+		}
+		if !errors.Is(err, c.kind) {
+			t.Fatalf("case %d: expect %s, got: %v", i, c.kind, err)
+		}
+	}
+
+	resp := &http.Response{StatusCode: http.StatusUnauthorized, Request: &http.Request{URL: apiURL}}
+	err := checkResponse(resp, []byte(`{"error":{"code":100,"message":"need login","name":"AuthenticationInvalidRequest"}}`))
+	ae := &APIError{}
+	if !errors.As(err, &ae) || ae.Code != 100 || ae.Name != "AuthenticationInvalidRequest" || ae.Message != "need login" {
+		t.Fatalf("unexpected api error: %+v", ae)
+	}
+}
+
+func TestFakeStatus(t *testing.T) {
+	ctx := context.Background()
+	cases := []struct {
+		status   int
+		kind     error
+		requests int
+	}{
+		{http.StatusNotFound, ErrGone, 1},
+		{http.StatusUnauthorized, ErrNotLoggedIn, 1},
+		{http.StatusInternalServerError, ErrServer, retryCountLimit},
+	}
+	for _, c := range cases {
+		zh, server := newFakeZhiHu(&fakezhihu.Config{ErrorRate: 1, ErrorStatus: c.status})
+		_, err := zh.getFolloweeOrFollower(ctx, zh.api.FolloweeURL(server.URLToken(0)))
+		if !errors.Is(err, c.kind) {
+			t.Fatalf("status %d: expect %s, got: %v", c.status, c.kind, err)
+		}
+		if server.Requests() != c.requests {
+			t.Fatalf("status %d: expect %d requests, got: %d", c.status, c.requests, server.Requests())
+		}
+		server.Close()
+	}
+}
+
+func TestAPIConfig(t *testing.T) {
+	api := &APIConfig{}
+	api.setDefault()
+	expect := "https://www.zhihu.com/api/v4/members/a/followees?offset=0&limit=20&include=" + defaultFollowInclude
+	if got := api.FolloweeURL("a"); got != expect {
+		t.Fatalf("unexpected followee url: %s", got)
+	}
+
+	api = &APIConfig{
+		BaseURL:       "http://127.0.0.1:8080/",
+		TopicIDAPI:    "https://mirror.example.com/topics/%s/children",
+		FollowInclude: "data[*].follower_count",
+	}
+	api.setDefault()
+	if got := api.TopicIDURL("1"); got != "https://mirror.example.com/topics/1/children" {
+		t.Fatalf("unexpected topic id url: %s", got)
+	}
+	if got := api.FollowerURL("a"); got != "http://127.0.0.1:8080/api/v4/members/a/followers?offset=0&limit=20&include=data[*].follower_count" {
+		t.Fatalf("unexpected follower url: %s", got)
+	}
+}
+
+func TestClientLimits(t *testing.T) {
+	ctx := context.Background()
+	zh, server := newFakeZhiHu(nil)
+	defer server.Close()
+
+	zh.maxBodySize = 16
+	if _, err := zh.fetchPeople(ctx, server.URLToken(0)); err == nil {
+		t.Fatalf("expect error when body too large")
+	}
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer slow.Close()
+
+	client, err := newClient(&HTTPConfig{Timeout: "50ms"})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	zh.client = client
+	zh.maxBodySize = defaultMaxBodySize
+	if _, err := zh.get(ctx, slow.URL); err == nil {
+		t.Fatalf("expect timeout error")
+	}
+
+	if _, err := newClient(&HTTPConfig{DialTimeout: "1x"}); err == nil {
+		t.Fatalf("expect error when parse invalid duration")
+	}
+}
+
+func TestParseRobots(t *testing.T) {
+	data := []byte(`
+User-agent: Googlebot
+User-agent: zhihu-research
+Disallow: /people/*/activities$
+Allow: /api/
+Disallow: /api/v4/members/*/followers
+Crawl-delay: 2
+
+User-agent: *
+Disallow: /
+`)
+
+	rules := parseRobots(data, "zhihu-research/1.0")
+	cases := map[string]bool{
+		"/api/v4/members/a/followees?offset=0": true,
+		"/api/v4/members/a/followers?offset=0": false,
+		"/people/a/activities":                 false,
+		"/people/a/activities?x=1":             true,
+		"/topic/1/hot":                         true,
+	}
+	for path, allowed := range cases {
+		if rules.allowed(path) != allowed {
+			t.Fatalf("path: %s, expect allowed: %t", path, allowed)
+		}
+	}
+	if rules.crawlDelay != 2*time.Second {
+		t.Fatalf("unexpected crawl delay: %s", rules.crawlDelay)
+	}
+
+	if parseRobots(data, "").allowed("/topic/1/hot") {
+		t.Fatalf("expect disallow all for default agent")
+	}
+}
+
+func TestFakeRobots(t *testing.T) {
+	ctx := context.Background()
+	zh, server := newFakeZhiHu(&fakezhihu.Config{
+		Robots: "User-agent: *\nDisallow: /people/\nCrawl-delay: 0.01\n",
+	})
+	defer server.Close()
+
+	robots, err := newRobotsPolicy(&RobotsConfig{Enable: true}, "", zh.client, zh.limiter)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	zh.robots = robots
+
+	if _, err := zh.fetchPeople(ctx, server.URLToken(0)); !errors.Is(err, ErrDisallowed) {
+		t.Fatalf("expect ErrDisallowed, got: %v", err)
+	}
+	if _, err := zh.getFolloweeOrFollower(ctx, zh.api.FolloweeURL(server.URLToken(0))); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if zh.limiter.interval != 10*time.Millisecond {
+		t.Fatalf("expect crawl delay in limiter, got: %s", zh.limiter.interval)
+	}
+}
+
+func TestFakeRobotsRetry(t *testing.T) {
+	ctx := context.Background()
+	zh, server := newFakeZhiHu(&fakezhihu.Config{
+		Robots:       "User-agent: *\nDisallow: /people/\n\nUser-agent: zhihu-research\nDisallow: /api/\n",
+		RobotsErrors: 2,
+	})
+	defer server.Close()
+
+	// robots.txt 和其他请求使用同一个 User-Agent
+	zh.userAgent = "zhihu-research/1.0"
+	robots, err := newRobotsPolicy(&RobotsConfig{Enable: true}, zh.userAgent, zh.client, zh.limiter)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	robots.retryBackoff = time.Millisecond
+	zh.robots = robots
+
+	// 5xx 时重试, 而不是禁止访问所有路径; 并发的请求只获取一次
+	var wg sync.WaitGroup
+	errs := make([]error, 5)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = robots.check(ctx, server.URL+"/people/"+server.URLToken(0))
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+	}
+	agents := server.RobotsAgents()
+	if len(agents) != 3 {
+		t.Fatalf("expect 3 robots.txt requests, got: %d", len(agents))
+	}
+	for _, agent := range agents {
+		if agent != zh.userAgent {
+			t.Fatalf("unexpected robots.txt user agent: %s", agent)
+		}
+	}
+	if _, err := zh.getFolloweeOrFollower(ctx, zh.api.FolloweeURL(server.URLToken(0))); !errors.Is(err, ErrDisallowed) {
+		t.Fatalf("expect ErrDisallowed, got: %v", err)
+	}
+}
+
+func TestJobConfigs(t *testing.T) {
+	jobs, err := jobConfigs(&ZhiHuConfig{Mode: collectTopic})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(jobs) != 1 || jobs[0].Name != "mode-3" || jobs[0].Mode != collectTopic {
+		t.Fatalf("unexpected default job: %+v", jobs)
+	}
+
+	jobs, err = jobConfigs(&ZhiHuConfig{Jobs: []*JobConfig{
+		{Name: "token", Mode: collectURLToken},
+		{Mode: collectPeople},
+	}})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if jobs[0].Name != "token" || jobs[1].Name != "mode-4" {
+		t.Fatalf("unexpected job names: %s, %s", jobs[0].Name, jobs[1].Name)
+	}
+
+	invalid := [][]*JobConfig{
+		{{Name: "a", Mode: collectTopic}, {Name: "a", Mode: collectPeople}},
+		{{Name: "a", Mode: collectTopic}, {Name: "b", Mode: collectTopic}},
+		{{Name: "a", Mode: 100}},
+		{{Name: "a", Mode: collectPipeline}, {Name: "b", Mode: collectPeople}},
+	}
+	for _, jc := range invalid {
+		if _, err := jobConfigs(&ZhiHuConfig{Jobs: jc}); err == nil {
+			t.Fatalf("expect error for jobs: %+v", jc)
+		}
+	}
+}
+
+func TestJobProgress(t *testing.T) {
+	job, err := newJob(&JobConfig{Name: "people", Mode: collectPeople})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	var nilJob *CollectJob
+	nilJob.addPage(1)
+
+	job.addPage(3)
+	job.addPage(2)
+	jp := job.Progress()
+	if jp.Running || jp.Pages != 2 || jp.Items != 5 {
+		t.Fatalf("unexpected progress: %s", jp)
+	}
+}
+
+func TestCollectPipeline(t *testing.T) {
+	zh, server := newFakeZhiHu(&fakezhihu.Config{Users: 30, Follows: 3, PageSize: 2})
+	defer server.Close()
+	zh.config.PipelineQueueSize = 2
+	ds := zh.dataSource.(*memStore)
+
+	// 采集到几个用户后中断, 队列中剩下的用户在下次运行时继续采集
+	ctx, cancel := context.WithCancel(context.Background())
+	job := newTestJob(t, &JobConfig{Mode: collectPipeline})
+	finish := make(chan struct{})
+	go func() {
+		defer close(finish)
+		zh.CollectPipeline(ctx, job)
+	}()
+	for job.Progress().Profiles < 3 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-finish
+
+	pp, err := ds.GetPeopleProgress(context.Background())
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	for id := uint64(1); id < pp.URLTokenID; id++ {
+		if _, ok := ds.people[id]; !ok {
+			t.Fatalf("people %d before progress %d not crawled", id, pp.URLTokenID)
+		}
+	}
+
+	job = newTestJob(t, &JobConfig{Mode: collectPipeline})
+	zh.CollectPipeline(context.Background(), job)
+	if len(ds.people) != len(ds.urlTokens) {
+		t.Fatalf("unexpected people: %d, url tokens: %d", len(ds.people), len(ds.urlTokens))
+	}
+	jp := job.Progress()
+	if jp.Profiles == 0 || jp.Pages == 0 {
+		t.Fatalf("unexpected progress: %s", jp)
+	}
+}
+
+func TestStopJob(t *testing.T) {
+	zh, server := newFakeZhiHu(nil)
+	defer server.Close()
+
+	hotList := newTestJob(t, &JobConfig{Name: "hot", Mode: collectHotList, Schedule: "@every 1h"})
+	search := newTestJob(t, &JobConfig{Name: "search", Mode: collectSearch, Schedule: "@every 1h"})
+	zh.jobs = []*CollectJob{hotList, search}
+	zh.stopFinish = make(chan struct{})
+	zh.Start(context.Background())
+
+	if err := zh.StopJob("unknown"); err == nil {
+		t.Fatalf("expect error when stop unknown job")
+	}
+	if err := zh.StopJob("hot"); err != nil {
+		t.Fatalf("%s", err)
+	}
+	select {
+	case <-hotList.Done():
+	default:
+		t.Fatalf("expect job hot done")
+	}
+	select {
+	case <-search.Done():
+		t.Fatalf("expect job search still running")
+	default:
+	}
+	if !hotList.Progress().Stopped || search.Progress().Stopped {
+		t.Fatalf("unexpected progress: %s, %s", hotList.Progress(), search.Progress())
+	}
+
+	zh.Stop()
+}
+
+func TestJobEnqueue(t *testing.T) {
+	var nilJob *CollectJob
+	nilJob.enqueue(context.Background(), []*URLToken{{ID: 1}})
+
+	job, err := newJob(&JobConfig{Mode: collectPipeline})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	job.queue = make(chan *URLToken, 1)
+
+	// 队列满时等待, ctx 取消后返回
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	job.enqueue(ctx, []*URLToken{{ID: 1}, {ID: 2}})
+	if len(job.queue) != 1 || (<-job.queue).ID != 1 {
+		t.Fatalf("unexpected queue")
+	}
+
+	if _, err = jobConfigs(&ZhiHuConfig{Jobs: []*JobConfig{
+		{Mode: collectURLToken},
+		{Mode: collectPipeline},
+	}}); err == nil {
+		t.Fatalf("expect error when pipeline and url token job share progress")
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	base := time.Date(2020, 3, 2, 10, 30, 20, 0, time.Local) // 周一
+
+	cases := []struct {
+		spec string
+		next time.Time
+	}{
+		{"0 3 * * 1", time.Date(2020, 3, 9, 3, 0, 0, 0, time.Local)},
+		{"*/15 * * * *", time.Date(2020, 3, 2, 10, 45, 0, 0, time.Local)},
+		{"0 0 1 * *", time.Date(2020, 4, 1, 0, 0, 0, 0, time.Local)},
+		{"30 2 29 2 *", time.Date(2024, 2, 29, 2, 30, 0, 0, time.Local)},
+		{"0 12 15 * 7", time.Date(2020, 3, 2+6, 12, 0, 0, 0, time.Local)},
+		{"5-10/5 11,12 * * *", time.Date(2020, 3, 2, 11, 5, 0, 0, time.Local)},
+		{"@daily", time.Date(2020, 3, 3, 0, 0, 0, 0, time.Local)},
+		{"@every 90m", base.Add(90 * time.Minute)},
+		{"@continuous", time.Time{}},
+	}
+	for _, c := range cases {
+		sched, err := parseSchedule(c.spec)
+		if err != nil {
+			t.Fatalf("%s: %s", c.spec, err)
+		}
+		if next := sched.next(base); !next.Equal(c.next) {
+			t.Fatalf("unexpected next of %s: %s, expect: %s", c.spec, next, c.next)
+		}
+	}
+
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "@every -1s", "a * * * *"} {
+		if _, err := parseSchedule(spec); err == nil {
+			t.Fatalf("expect error for schedule: %q", spec)
+		}
+	}
+
+	if _, err := newJob(&JobConfig{Name: "a", Mode: collectPeople, Missed: "later"}); err == nil {
+		t.Fatalf("expect error for unexpected missed policy")
+	}
+}
+
+func TestCheckMissed(t *testing.T) {
+	zh := &ZhiHu{}
+	job, err := newJob(&JobConfig{Name: "a", Mode: collectPeople, Schedule: "@every 1h"})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	past := time.Now().Add(-time.Minute)
+	if next := zh.checkMissed(job, past); next.Before(time.Now()) {
+		t.Fatalf("expect skip to next run, got: %s", next)
+	}
+	job.config.Missed = missedRun
+	if next := zh.checkMissed(job, past); !next.IsZero() {
+		t.Fatalf("expect run now, got: %s", next)
+	}
+	future := time.Now().Add(time.Hour)
+	if next := zh.checkMissed(job, future); !next.Equal(future) {
+		t.Fatalf("unexpected next: %s", next)
+	}
+}
+
+func TestRefreshAge(t *testing.T) {
+	job, err := newJob(&JobConfig{Name: "refresh", Mode: refreshPeople})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if job.refreshAge != defaultRefreshAge {
+		t.Fatalf("unexpected default refresh age: %s", job.refreshAge)
+	}
+
+	job, err = newJob(&JobConfig{Name: "refresh", Mode: refreshPeople, RefreshAge: "168h"})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if job.refreshAge != 7*24*time.Hour {
+		t.Fatalf("unexpected refresh age: %s", job.refreshAge)
+	}
+
+	if _, err := newJob(&JobConfig{Name: "refresh", Mode: refreshPeople, RefreshAge: "week"}); err == nil {
+		t.Fatalf("expect error for invalid refresh age")
+	}
+
+	fields := (&People{}).ToInsert()
+	if crawlTime, ok := fields[len(fields)-1].(time.Time); !ok || crawlTime.IsZero() {
+		t.Fatalf("expect crawl time when insert people")
+	}
+}
+
+func TestJobLimits(t *testing.T) {
+	var nilJob *CollectJob
+	if nilJob.allowNodes(5) != 5 || nilJob.reachMaxDepth(100) {
+		t.Fatalf("nil job should not limit")
+	}
+
+	job, err := newJob(&JobConfig{Name: "bfs", Mode: collectURLToken, MaxDepth: 2, MaxNodes: 10, MaxDuration: "1h"})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if job.maxDuration != time.Hour {
+		t.Fatalf("unexpected max duration: %s", job.maxDuration)
+	}
+	if job.reachMaxDepth(1) || !job.reachMaxDepth(2) {
+		t.Fatalf("unexpected max depth")
+	}
+
+	job.setNodes(7)
+	if allow := job.allowNodes(5); allow != 3 {
+		t.Fatalf("unexpected allow nodes: %d", allow)
+	}
+	job.addNodes(3)
+	if allow := job.allowNodes(5); allow != 0 || !job.reachMaxNodes() {
+		t.Fatalf("expect reach max nodes, allow: %d", allow)
+	}
+
+	if _, err := newJob(&JobConfig{Name: "bfs", Mode: collectURLToken, MaxDepth: -1}); err == nil {
+		t.Fatalf("expect error for negative max depth")
+	}
+}
+
+func TestJobScore(t *testing.T) {
+	job, err := newJob(&JobConfig{Name: "bfs", Mode: collectURLToken})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if score := job.score(&Followee{FollowerCount: 1000}, 1); score != 0 {
+		t.Fatalf("expect zero score without priority, got: %f", score)
+	}
+
+	job, err = newJob(&JobConfig{Name: "priority", Mode: collectURLToken, Priority: &PriorityConfig{
+		FollowerWeight: 1,
+		DepthWeight:    0.5,
+		OrgPenalty:     100,
+	}})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	popular := job.score(&Followee{FollowerCount: 999}, 1)
+	if popular != 2.5 {
+		t.Fatalf("unexpected score: %f", popular)
+	}
+	if deeper := job.score(&Followee{FollowerCount: 999}, 3); deeper >= popular {
+		t.Fatalf("expect deeper user has lower score: %f", deeper)
+	}
+	if org := job.score(&Followee{FollowerCount: 999999, IsOrg: true}, 1); org >= 0 {
+		t.Fatalf("expect org account has lowest score: %f", org)
+	}
+}
+
+func TestJobDirection(t *testing.T) {
+	job, err := newJob(&JobConfig{Name: "bfs", Mode: collectURLToken})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if job.config.Direction != directionBoth {
+		t.Fatalf("unexpected default direction: %s", job.config.Direction)
+	}
+
+	invalid := []*JobConfig{
+		{Name: "a", Mode: collectURLToken, Direction: "friends"},
+		{Name: "b", Mode: collectURLToken, MaxFollowerPages: -1},
+	}
+	for _, jc := range invalid {
+		if _, err := newJob(jc); err == nil {
+			t.Fatalf("expect error for job: %+v", jc)
+		}
+	}
+}
+
+func TestFakeTopicDetail(t *testing.T) {
+	ctx := context.Background()
+
+	for _, disable := range []bool{false, true} {
+		zh, server := newFakeZhiHu(&fakezhihu.Config{Topics: 10, DisableTopicAPI: disable})
+		followerCount, questionCount := server.TopicCounts(4)
+
+		ti := &TopicID{ID: 5, TopicID: server.TopicID(4), Name: "topic-4"}
+		tt, err := zh.fetchTopicDetail(ctx, ti)
+		server.Close()
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+
+		if tt.TopicID != 5 || tt.Name != "topic-4" ||
+			tt.FollowerCount != uint64(followerCount) || tt.QuestionCount != uint64(questionCount) {
+			t.Fatalf("unexpected topic: %+v, disable api: %t", tt, disable)
+		}
+		// 使用页面采集时没有简介和优秀答主数
+		if disable != (tt.Introduction == "") || disable != (tt.BestAnswerersCount == 0) {
+			t.Fatalf("unexpected topic detail: %+v, disable api: %t", tt, disable)
+		}
+	}
+
+	// 话题不存在时不再请求页面
+	zh, server := newFakeZhiHu(&fakezhihu.Config{Topics: 10})
+	defer server.Close()
+	if _, err := zh.fetchTopicDetail(ctx, &TopicID{ID: 1, TopicID: "1"}); !errors.Is(err, ErrGone) || server.Requests() != 1 {
+		t.Fatalf("unexpected err: %v, requests: %d", err, server.Requests())
+	}
+}
+
+// cancelStore 在保存 pages 页回答或评论后取消 ctx, 用于测试中断后继续
+type cancelStore struct {
+	*memStore
+	cancel func()
+	pages  int
+}
+
+func (cs *cancelStore) countPage() {
+	if cs.pages--; cs.pages == 0 {
+		cs.cancel()
+	}
+}
+
+func (cs *cancelStore) InsertAnswers(ctx context.Context, answers []*AnswerTable) error {
+	if err := cs.memStore.InsertAnswers(ctx, answers); err != nil {
+		return err
+	}
+	cs.countPage()
+	return nil
+}
+
+func (cs *cancelStore) InsertComments(ctx context.Context, comments []*CommentTable) error {
+	if err := cs.memStore.InsertComments(ctx, comments); err != nil {
+		return err
+	}
+	cs.countPage()
+	return nil
+}
+
+func (cs *cancelStore) InsertURLTokens(ctx context.Context, urlTokens []*URLToken) ([]*URLToken, error) {
+	inserted, err := cs.memStore.InsertURLTokens(ctx, urlTokens)
+	if err != nil {
+		return nil, err
+	}
+	cs.countPage()
+	return inserted, nil
+}
+
+func answerIDs(ds *memStore) []string {
+	var ids []string
+	for _, answer := range ds.answers {
+		ids = append(ids, answer.AnswerID)
+	}
+	return ids
+}
+
+func TestCollectQuestionAnswers(t *testing.T) {
+	ctx := context.Background()
+	zh, server := newFakeZhiHu(&fakezhihu.Config{Users: 10, Questions: 3, Answers: 7, PageSize: 3})
+	defer server.Close()
+	// 默认地址中 limit=20, 使用服务的分页大小
+	zh.api.QuestionAnswersAPI = "/api/v4/questions/%s/answers"
+	ds := zh.dataSource.(*memStore)
+
+	// 配置了问题时只采集这些问题, 不保存进度
+	job := newTestJob(t, &JobConfig{Mode: collectQuestionAnswers, QuestionIDs: []string{server.QuestionID(1), "1"}})
+	zh.CollectQuestionAnswers(ctx, job)
+	if fmt.Sprint(answerIDs(ds)) != fmt.Sprint(server.AnswerIDs(1)) {
+		t.Fatalf("unexpected answers: %v, expect: %v", answerIDs(ds), server.AnswerIDs(1))
+	}
+	if jp := job.Progress(); jp.Pages != 3 || jp.Items != 7 || len(ds.answerProgress) != 0 {
+		t.Fatalf("unexpected progress: %s, answerProgress: %d", jp, len(ds.answerProgress))
+	}
+	for _, answer := range ds.answers {
+		if answer.QuestionID != server.QuestionID(1) || answer.AuthorURLToken == "" ||
+			answer.CreatedTime.IsZero() || answer.Content == "" {
+			t.Fatalf("unexpected answer: %+v", answer)
+		}
+	}
+
+	// 没有配置问题时遍历 question 表, 每个问题最多 MaxPages 页
+	ds = newMemStore()
+	zh.dataSource = ds
+	if err := ds.InsertQuestions(ctx, []*Question{{QuestionID: server.QuestionID(0)}, {QuestionID: server.QuestionID(2)}}); err != nil {
+		t.Fatalf("%s", err)
+	}
+	zh.CollectQuestionAnswers(ctx, newTestJob(t, &JobConfig{Mode: collectQuestionAnswers, MaxPages: 2}))
+	if len(ds.answers) != 12 || len(ds.answerProgress) != 1 || ds.answerProgress[0].QuestionID != 3 {
+		t.Fatalf("unexpected answers: %d, progress: %+v", len(ds.answers), ds.answerProgress)
+	}
+
+	// 中断后从保存的那一页继续
+	ds = newMemStore()
+	if err := ds.InsertQuestions(ctx, []*Question{{QuestionID: server.QuestionID(0)}, {QuestionID: server.QuestionID(2)}}); err != nil {
+		t.Fatalf("%s", err)
+	}
+	cancelCtx, cancel := context.WithCancel(ctx)
+	zh.dataSource = &cancelStore{memStore: ds, cancel: cancel, pages: 4}
+	zh.CollectQuestionAnswers(cancelCtx, newTestJob(t, &JobConfig{Mode: collectQuestionAnswers}))
+	if len(ds.answers) != 10 || len(ds.answerProgress) != 1 ||
+		ds.answerProgress[0].QuestionID != 2 || ds.answerProgress[0].NextAnswerURL == "" {
+		t.Fatalf("unexpected answers: %d, progress: %+v", len(ds.answers), ds.answerProgress)
+	}
+
+	zh.dataSource = ds
+	job = newTestJob(t, &JobConfig{Mode: collectQuestionAnswers})
+	zh.CollectQuestionAnswers(ctx, job)
+	expect := append(server.AnswerIDs(0), server.AnswerIDs(2)...)
+	if fmt.Sprint(answerIDs(ds)) != fmt.Sprint(expect) || job.Progress().Items != 4 {
+		t.Fatalf("unexpected answers: %v, expect: %v, progress: %s", answerIDs(ds), expect, job.Progress())
+	}
+}
+
+func TestWalkStopWhenNotLoggedIn(t *testing.T) {
+	ctx := context.Background()
+	zh, server := newFakeZhiHu(&fakezhihu.Config{Questions: 3, ErrorRate: 1, ErrorStatus: http.StatusUnauthorized})
+	defer server.Close()
+	ds := zh.dataSource.(*memStore)
+	if err := ds.InsertQuestions(ctx, []*Question{{QuestionID: server.QuestionID(0)}, {QuestionID: server.QuestionID(2)}}); err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	// 登录失效时不跳过问题, 进度停在第一个问题的第一页
+	zh.CollectQuestionAnswers(ctx, newTestJob(t, &JobConfig{Mode: collectQuestionAnswers}))
+	expect := zh.api.QuestionAnswersURL(server.QuestionID(0))
+	if server.Requests() != 1 || len(ds.answerProgress) != 1 ||
+		ds.answerProgress[0].QuestionID != 1 || ds.answerProgress[0].NextAnswerURL != expect {
+		t.Fatalf("unexpected requests: %d, progress: %+v", server.Requests(), ds.answerProgress)
+	}
+}
+
+func TestCollectUserAnswers(t *testing.T) {
+	ctx := context.Background()
+	zh, server := newFakeZhiHu(&fakezhihu.Config{Users: 4, Questions: 5, Answers: 6, PageSize: 2})
+	defer server.Close()
+	zh.api.MemberAnswersAPI = "/api/v4/members/%s/answers"
+
+	// urlToken 的 id 不连续: 3, 6, 9, 12
+	insertUsers := func(ds *memStore) {
+		ds.urlTokenGap = 2
+		for i := 0; i < 4; i++ {
+			if _, err := ds.InsertURLTokens(ctx, []*URLToken{{URLToken: server.URLToken(i)}}); err != nil {
+				t.Fatalf("%s", err)
+			}
+		}
+	}
+	ds := zh.dataSource.(*memStore)
+	insertUsers(ds)
+
+	var expect []string
+	for i := 0; i < 4; i++ {
+		expect = append(expect, server.MemberAnswerIDs(i)...)
+	}
+	zh.CollectUserAnswers(ctx, newTestJob(t, &JobConfig{Mode: collectUserAnswers}))
+	if len(expect) == 0 || fmt.Sprint(answerIDs(ds)) != fmt.Sprint(expect) {
+		t.Fatalf("unexpected answers: %v, expect: %v", answerIDs(ds), expect)
+	}
+	// 按用户采集时记录作者在 urlToken 表中的 id
+	for _, answer := range ds.answers {
+		if ut := ds.urlTokenByID(answer.URLTokenID); ut == nil || ut.URLToken != answer.AuthorURLToken {
+			t.Fatalf("unexpected author of answer: %+v", answer)
+		}
+	}
+	if len(ds.userAnswerProgress) != 1 || ds.userAnswerProgress[0].URLTokenID != 13 {
+		t.Fatalf("unexpected userAnswerProgress: %+v", ds.userAnswerProgress)
+	}
+
+	// 进度中的 id 不存在时从下一个用户继续, 不会重复采集
+	ds = newMemStore()
+	zh.dataSource = ds
+	insertUsers(ds)
+	if err := ds.InsertUserAnswerProgress(ctx, &UserAnswerProgress{URLTokenID: 7}); err != nil {
+		t.Fatalf("%s", err)
+	}
+	zh.CollectUserAnswers(ctx, newTestJob(t, &JobConfig{Mode: collectUserAnswers}))
+	expect = append(server.MemberAnswerIDs(2), server.MemberAnswerIDs(3)...)
+	if fmt.Sprint(answerIDs(ds)) != fmt.Sprint(expect) {
+		t.Fatalf("unexpected answers: %v, expect: %v", answerIDs(ds), expect)
+	}
+}
+
+func articleIDs(ds *memStore) []string {
+	var ids []string
+	for _, article := range ds.articles {
+		ids = append(ids, article.ArticleID)
+	}
+	return ids
+}
+
+func TestCollectUserArticles(t *testing.T) {
+	ctx := context.Background()
+	zh, server := newFakeZhiHu(&fakezhihu.Config{Users: 10, PageSize: 2})
+	defer server.Close()
+	zh.api.MemberArticlesAPI = "/api/v4/members/%s/articles"
+	zh.api.MemberColumnsAPI = "/api/v4/members/%s/column-contributions"
+
+	// urlToken 的 id 不连续: 3, 6
+	insertUsers := func(ds *memStore) {
+		ds.urlTokenGap = 2
+		for _, i := range []int{5, 6} {
+			if _, err := ds.InsertURLTokens(ctx, []*URLToken{{URLToken: server.URLToken(i)}}); err != nil {
+				t.Fatalf("%s", err)
+			}
+		}
+	}
+	ds := zh.dataSource.(*memStore)
+	insertUsers(ds)
+
+	zh.CollectUserArticles(ctx, newTestJob(t, &JobConfig{Mode: collectUserArticles}))
+	expect := append(server.ArticleIDs(5), server.ArticleIDs(6)...)
+	if len(expect) == 0 || fmt.Sprint(articleIDs(ds)) != fmt.Sprint(expect) {
+		t.Fatalf("unexpected articles: %v, expect: %v", articleIDs(ds), expect)
+	}
+	for _, article := range ds.articles {
+		if ut := ds.urlTokenByID(article.URLTokenID); ut == nil || ut.URLToken != article.AuthorURLToken ||
+			(article.AuthorURLToken == server.URLToken(5) && article.ColumnID != server.ColumnID(5)) {
+			t.Fatalf("unexpected article: %+v", article)
+		}
+	}
+	// 只保留用户自己的专栏, 6 号用户没有专栏
+	for _, ct := range ds.columns {
+		if ct.ColumnID == server.ColumnID(5) && ct.AuthorURLToken != server.URLToken(5) {
+			t.Fatalf("unexpected column: %+v", ct)
+		}
+	}
+	if server.ColumnID(6) != "" || len(ds.userArticleProgress) != 1 || ds.userArticleProgress[0].URLTokenID != 7 {
+		t.Fatalf("unexpected userArticleProgress: %+v", ds.userArticleProgress)
+	}
+
+	// 文章已经采集完时只继续采集专栏
+	ds = newMemStore()
+	zh.dataSource = ds
+	insertUsers(ds)
+	uap := &UserArticleProgress{URLTokenID: 3, NextColumnURL: zh.api.MemberColumnsURL(server.URLToken(5))}
+	if err := ds.InsertUserArticleProgress(ctx, uap); err != nil {
+		t.Fatalf("%s", err)
+	}
+	zh.CollectUserArticles(ctx, newTestJob(t, &JobConfig{Mode: collectUserArticles}))
+	if fmt.Sprint(articleIDs(ds)) != fmt.Sprint(server.ArticleIDs(6)) {
+		t.Fatalf("unexpected articles: %v, expect: %v", articleIDs(ds), server.ArticleIDs(6))
+	}
+	if len(ds.columns) == 0 || ds.columns[0].ColumnID != server.ColumnID(5) {
+		t.Fatalf("expect own column of user 5, got: %d", len(ds.columns))
+	}
+}
+
+func TestCollectColumnArticles(t *testing.T) {
+	ctx := context.Background()
+	zh, server := newFakeZhiHu(&fakezhihu.Config{Users: 10, PageSize: 2})
+	defer server.Close()
+	zh.api.ColumnArticlesAPI = "/api/v4/columns/%s/items"
+
+	// 已有的专栏标题不会被配置中的专栏覆盖
+	ds := zh.dataSource.(*memStore)
+	columns := []*ColumnTable{{ColumnID: server.ColumnID(0), Title: "column-0"}, {ColumnID: server.ColumnID(5), Title: "column-5"}}
+	if err := ds.InsertColumns(ctx, columns); err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	// 配置了专栏时只采集这些专栏, 不保存进度; 专栏的列表中不是文章的数据需要跳过
+	zh.CollectColumnArticles(ctx, newTestJob(t, &JobConfig{Mode: collectColumnArticles, ColumnIDs: []string{server.ColumnID(5)}}))
+	if ds.columns[1].Title != "column-5" {
+		t.Fatalf("unexpected column: %+v", ds.columns[1])
+	}
+	if fmt.Sprint(articleIDs(ds)) != fmt.Sprint(server.ArticleIDs(5)) {
+		t.Fatalf("unexpected articles: %v, expect: %v", articleIDs(ds), server.ArticleIDs(5))
+	}
+	for _, article := range ds.articles {
+		if article.URLTokenID != 0 || article.ColumnID != server.ColumnID(5) {
+			t.Fatalf("unexpected article: %+v", article)
+		}
+	}
+	if len(ds.columnArticleProgress) != 0 {
+		t.Fatalf("unexpected columnArticleProgress: %+v", ds.columnArticleProgress)
+	}
+
+	// 没有配置专栏时遍历 column 表
+	zh.CollectColumnArticles(ctx, newTestJob(t, &JobConfig{Mode: collectColumnArticles}))
+	expect := append(server.ArticleIDs(5), server.ArticleIDs(0)...)
+	if fmt.Sprint(articleIDs(ds)) != fmt.Sprint(expect) {
+		t.Fatalf("unexpected articles: %v, expect: %v", articleIDs(ds), expect)
+	}
+	if len(ds.columnArticleProgress) != 1 || ds.columnArticleProgress[0].ColumnID != 3 {
+		t.Fatalf("unexpected columnArticleProgress: %+v", ds.columnArticleProgress)
+	}
+}
+
+func TestCollectTopicQuestions(t *testing.T) {
+	ctx := context.Background()
+	zh, server := newFakeZhiHu(&fakezhihu.Config{Topics: 10, Questions: 8, PageSize: 2})
+	defer server.Close()
+	zh.api.TopicTopAPI = "/api/v4/topics/%s/feeds/top_activity"
+	zh.api.TopicEssenceAPI = "/api/v4/topics/%s/feeds/essence"
+	zh.api.TopicTimelineAPI = "/api/v4/topics/%s/feeds/timeline_question"
+
+	ds := zh.dataSource.(*memStore)
+	if err := ds.InsertTopicsID(ctx, []*TopicID{{TopicID: server.TopicID(7)}}); err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	job := newTestJob(t, &JobConfig{Mode: collectTopicQuestions})
+	zh.CollectTopicQuestions(ctx, job)
+
+	// 回答所属的问题和等待回答的问题都需要保留, 文章需要跳过
+	var expect []string
+	for _, q := range server.TopicQuestions(7) {
+		expect = append(expect, server.QuestionID(q))
+	}
+	// 每个列表中的问题相同, 只记录第一次出现的列表
+	var questionIDs []string
+	for _, tq := range ds.topicQuestions {
+		if tq.TopicID != server.TopicID(7) || tq.Feed != job.config.TopicFeeds[0] {
+			t.Fatalf("unexpected topic question: %+v", tq)
+		}
+		questionIDs = append(questionIDs, tq.QuestionID)
+	}
+	if fmt.Sprint(questionIDs) != fmt.Sprint(expect) {
+		t.Fatalf("unexpected questions: %v, expect: %v", questionIDs, expect)
+	}
+	if jp := job.Progress(); jp.Pages < uint64(len(job.config.TopicFeeds)) {
+		t.Fatalf("expect all feeds collected, progress: %s", jp)
+	}
+	for _, question := range ds.questions {
+		if question.AnswerCount == 0 || question.CreatedTime.IsZero() {
+			t.Fatalf("unexpected question: %+v", question)
+		}
+	}
+	if len(ds.questions) != len(expect) || len(ds.topicQuestionProgress) != 1 {
+		t.Fatalf("unexpected questions: %d, progress: %+v", len(ds.questions), ds.topicQuestionProgress)
+	}
+
+	if _, err := newJob(&JobConfig{Name: "a", Mode: collectTopicQuestions, TopicFeeds: []string{"hot"}}); err == nil {
+		t.Fatal("expect error for unexpected topic feed")
+	}
+}
+
+func TestCollectComments(t *testing.T) {
+	ctx := context.Background()
+	zh, server := newFakeZhiHu(&fakezhihu.Config{Users: 10, PageSize: 2})
+	defer server.Close()
+	zh.api.AnswerCommentsAPI = "/api/v4/answers/%s/root_comments"
+	zh.api.ChildCommentsAPI = "/api/v4/comments/%s/child_comments"
+
+	answerID := "100000003"
+	ds := zh.dataSource.(*memStore)
+	if err := ds.InsertAnswers(ctx, []*AnswerTable{{AnswerID: answerID}}); err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	// 第一条根评论的子评论采集完后中断, 下次从这一页根评论重新开始
+	cancelCtx, cancel := context.WithCancel(ctx)
+	zh.dataSource = &cancelStore{memStore: ds, cancel: cancel, pages: 2}
+	zh.CollectComments(cancelCtx, newTestJob(t, &JobConfig{Mode: collectComments}))
+	if len(ds.commentProgress) != 1 || ds.commentProgress[0].ResourceID != 1 ||
+		ds.commentProgress[0].NextCommentURL != zh.api.RootCommentsURL(commentResourceAnswer, answerID) {
+		t.Fatalf("unexpected commentProgress: %+v", ds.commentProgress)
+	}
+
+	zh.dataSource = ds
+	zh.CollectComments(ctx, newTestJob(t, &JobConfig{Mode: collectComments}))
+
+	var expectRoots, expectChildren []string
+	for _, id := range server.RootCommentIDs(100000003) {
+		expectRoots = append(expectRoots, strconv.Itoa(id))
+		for _, child := range server.ChildCommentIDs(id) {
+			expectChildren = append(expectChildren, strconv.Itoa(child))
+		}
+	}
+
+	authors := make(map[string]string)
+	var rootIDs, childIDs []string
+	for _, comment := range ds.comments {
+		if comment.ResourceType != commentResourceAnswer || comment.ResourceID != answerID || comment.AuthorURLToken == "" {
+			t.Fatalf("unexpected comment: %+v", comment)
+		}
+		if comment.RootCommentID == "" {
+			authors[comment.CommentID] = comment.AuthorURLToken
+			rootIDs = append(rootIDs, comment.CommentID)
+			continue
+		}
+		// 子评论记录所属的根评论和回复的用户
+		if comment.ReplyToURLToken != authors[comment.RootCommentID] {
+			t.Fatalf("unexpected child comment: %+v", comment)
+		}
+		childIDs = append(childIDs, comment.CommentID)
+	}
+	sort.Strings(childIDs)
+	sort.Strings(expectChildren)
+	if len(rootIDs) == 0 || fmt.Sprint(rootIDs) != fmt.Sprint(expectRoots) {
+		t.Fatalf("unexpected root comments: %v, expect: %v", rootIDs, expectRoots)
+	}
+	if len(childIDs) == 0 || fmt.Sprint(childIDs) != fmt.Sprint(expectChildren) {
+		t.Fatalf("unexpected child comments: %v, expect: %v", childIDs, expectChildren)
+	}
+	// 没有文章时不保存文章的进度
+	if len(ds.commentProgress) != 2 || ds.commentProgress[1].ResourceType != commentResourceAnswer ||
+		ds.commentProgress[1].ResourceID != 2 {
+		t.Fatalf("unexpected commentProgress: %+v", ds.commentProgress)
+	}
+}
+
+func TestParseHeat(t *testing.T) {
+	cases := map[string]uint64{
+		"1234 万热度": 12340000,
+		"1.2 亿热度":  120000000,
+		"356 热度":   356,
+		"热度":       0,
+	}
+	for text, expect := range cases {
+		if heat := parseHeat(text); heat != expect {
+			t.Fatalf("unexpected heat of %s: %d, expect: %d", text, heat, expect)
+		}
+	}
+}
+
+func TestCollectHotList(t *testing.T) {
+	ctx := context.Background()
+	zh, server := newFakeZhiHu(&fakezhihu.Config{Questions: 5, PageSize: 2})
+	defer server.Close()
+	zh.api.HotListAPI = "/api/v3/feed/topstory/hot-lists/total"
+
+	zh.CollectHotList(ctx, newTestJob(t, &JobConfig{Mode: collectHotList}))
+	ds := zh.dataSource.(*memStore)
+	if len(ds.hotList) != 5 || len(ds.questions) != 5 {
+		t.Fatalf("unexpected hot list size: %d, questions: %d", len(ds.hotList), len(ds.questions))
+	}
+	// 文章占用第 3 名, 之后的问题排名顺延
+	for q, ht := range ds.hotList {
+		expectRank := q + 1
+		if q >= 2 {
+			expectRank++
+		}
+		if ht.QuestionID != server.QuestionID(q) || ht.Rank != expectRank ||
+			ht.Heat != uint64(server.HotListHeat(q))*10000 || !ht.SnapshotTime.Equal(ds.hotList[0].SnapshotTime) {
+			t.Fatalf("unexpected hot list item %d: %+v", q, ht)
+		}
+		if ds.questions[q].QuestionID != ht.QuestionID || ds.questions[q].Title != ht.Title {
+			t.Fatalf("unexpected question of hot list item %d: %+v", q, ds.questions[q])
+		}
+	}
+
+	// 第二页出错时丢弃这次快照
+	zh, server = newFakeZhiHu(&fakezhihu.Config{Questions: 5, PageSize: 2,
+		Robots: "User-agent: *\nDisallow: /api/v3/feed/topstory/hot-lists/total?\n"})
+	defer server.Close()
+	zh.api.HotListAPI = "/api/v3/feed/topstory/hot-lists/total"
+	robots, err := newRobotsPolicy(&RobotsConfig{Enable: true}, "", zh.client, zh.limiter)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	zh.robots = robots
+
+	job := newTestJob(t, &JobConfig{Mode: collectHotList})
+	zh.CollectHotList(ctx, job)
+	ds = zh.dataSource.(*memStore)
+	if job.Progress().Pages != 1 || len(ds.hotList) != 0 || len(ds.questions) != 0 {
+		t.Fatalf("unexpected hot list size: %d, questions: %d, progress: %s", len(ds.hotList), len(ds.questions), job.Progress())
+	}
+}
+
+func TestCollectSearch(t *testing.T) {
+	ctx := context.Background()
+	zh, server := newFakeZhiHu(&fakezhihu.Config{Users: 10, Topics: 10, Questions: 5, PageSize: 2})
+	defer server.Close()
+	zh.api.SearchPeopleAPI = "/api/v4/search_v3?t=people&q=%s"
+	zh.api.SearchTopicAPI = "/api/v4/search_v3?t=topic&q=%s"
+	zh.api.SearchQuestionAPI = "/api/v4/search_v3?t=general&q=%s"
+
+	zh.CollectSearch(ctx, newTestJob(t, &JobConfig{Mode: collectSearch, Keywords: []string{"机器 学习"}}))
+	ds := zh.dataSource.(*memStore)
+
+	var urlTokens, topicsID, questionIDs []string
+	for _, ut := range ds.urlTokens {
+		urlTokens = append(urlTokens, ut.URLToken)
+	}
+	for _, ti := range ds.topicsID {
+		if strings.Contains(ti.Name, "<em>") || !strings.HasPrefix(ti.Name, "机器 学习") {
+			t.Fatalf("unexpected topic name: %s", ti.Name)
+		}
+		topicsID = append(topicsID, ti.TopicID)
+	}
+	for _, question := range ds.questions {
+		questionIDs = append(questionIDs, question.QuestionID)
+	}
+
+	var expectURLTokens, expectTopicsID, expectQuestionIDs []string
+	for i := 0; i < 5; i++ {
+		expectURLTokens = append(expectURLTokens, server.URLToken(i))
+		expectTopicsID = append(expectTopicsID, server.TopicID(i))
+		expectQuestionIDs = append(expectQuestionIDs, server.QuestionID(i))
+	}
+	if fmt.Sprint(urlTokens) != fmt.Sprint(expectURLTokens) {
+		t.Fatalf("unexpected urlTokens: %v, expect: %v", urlTokens, expectURLTokens)
+	}
+	if fmt.Sprint(topicsID) != fmt.Sprint(expectTopicsID) {
+		t.Fatalf("unexpected topics: %v, expect: %v", topicsID, expectTopicsID)
+	}
+	if fmt.Sprint(questionIDs) != fmt.Sprint(expectQuestionIDs) {
+		t.Fatalf("unexpected questions: %v, expect: %v", questionIDs, expectQuestionIDs)
+	}
+	// 全部搜索完后下次从第一个关键词开始
+	if len(ds.searchProgress) != 1 || ds.searchProgress[0].Keyword != "" {
+		t.Fatalf("unexpected searchProgress: %+v", ds.searchProgress)
+	}
+
+	// 搜索完一种类型后被取消时, 下次从下一种类型开始
+	ds = newMemStore()
+	cancelCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	zh.dataSource = &cancelStore{memStore: ds, cancel: cancel, pages: 3}
+	zh.CollectSearch(cancelCtx, newTestJob(t, &JobConfig{Mode: collectSearch, Keywords: []string{"机器 学习"}}))
+	if len(ds.urlTokens) != 5 || len(ds.searchProgress) != 1 || ds.searchProgress[0].SearchType != searchTopic ||
+		ds.searchProgress[0].NextSearchURL != zh.api.SearchURL(searchTopic, "机器 学习") {
+		t.Fatalf("unexpected urlTokens: %d, searchProgress: %+v", len(ds.urlTokens), ds.searchProgress)
+	}
+
+	if _, err := newJob(&JobConfig{Name: "a", Mode: collectSearch, SearchTypes: []string{"column"}}); err == nil {
+		t.Fatal("expect error for unexpected search type")
+	}
+}
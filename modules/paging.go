@@ -0,0 +1,73 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/astaxie/beego/logs"
+)
+
+// getWithRetry 在服务器出错时重试, 最多 retryCountLimit 次
+func (zh *ZhiHu) getWithRetry(ctx context.Context, url string) ([]byte, error) {
+	timer := time.NewTimer(zh.pauseDuration)
+	defer timer.Stop()
+
+	var retryCount int
+	for {
+		data, err := zh.get(ctx, url)
+		if err == nil || !errors.Is(err, ErrServer) {
+			return data, err
+		}
+
+		retryCount++
+		logs.Debug("get %s retry count: %d", url, retryCount)
+		if retryCount >= retryCountLimit {
+			logs.Error("retry count over, url: %s", url)
+			return nil, err
+		}
+
+		timer.Reset(zh.pauseDuration)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// pageHandler 解析并保存一页数据, 返回分页信息和数据条数
+type pageHandler func(ctx context.Context, data []byte) (*Paging, int, error)
+
+// continuePaging 从 startURL 开始依次请求每一页并交给 handle 处理, maxPages 为 0 时不限制页数.
+// 返回下次继续的位置, 所有页都处理完时返回空字符串; 出错时返回出错的那一页.
+func (zh *ZhiHu) continuePaging(ctx context.Context, job *CollectJob, startURL string, maxPages int, handle pageHandler) (string, error) {
+	ticker := time.NewTicker(zh.pauseDuration)
+	defer ticker.Stop()
+
+	nextURL := startURL
+	for pages := 0; maxPages == 0 || pages < maxPages; pages++ {
+		data, err := zh.getWithRetry(ctx, nextURL)
+		if err != nil {
+			return nextURL, err
+		}
+
+		paging, count, err := handle(ctx, data)
+		if err != nil {
+			return nextURL, err
+		}
+		job.addPage(count)
+
+		if count == 0 || paging == nil || paging.IsEnd || paging.Next == "" {
+			return "", nil
+		}
+		nextURL = paging.Next
+
+		select {
+		case <-ctx.Done():
+			return nextURL, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nextURL, nil
+}
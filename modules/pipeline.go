@@ -0,0 +1,118 @@
+package modules
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/astaxie/beego/logs"
+)
+
+const defaultPipelineQueueSize = 100
+
+// CollectPipeline 在采集 urlToken 的同时采集新发现用户的信息.
+// 队列满时 continueGetFollowee 会等待, 避免发现的速度远超采集的速度.
+func (zh *ZhiHu) CollectPipeline(ctx context.Context, job *CollectJob) {
+	// afterID 之前的用户都已经采集过, 包括上次中断时还在队列中的用户
+	var afterID uint64
+	pp, err := zh.dataSource.GetPeopleProgress(ctx)
+	if err != nil && err != sql.ErrNoRows {
+		logs.Error("error when get peopleProgress: %s", err)
+		return
+	} else if err == nil && pp.URLTokenID > 0 {
+		afterID = pp.URLTokenID - 1
+		logs.Info("load peopleProgress success")
+	}
+
+	size := zh.config.PipelineQueueSize
+	if size <= 0 {
+		size = defaultPipelineQueueSize
+	}
+	job.queue = make(chan *URLToken, size)
+
+	crawlFinish := make(chan struct{})
+	go zh.crawlQueuedPeople(ctx, job, afterID, crawlFinish)
+
+	zh.CollectURLToken(ctx, job)
+
+	// 发现结束后继续采集剩余的用户
+	close(job.queue)
+	<-crawlFinish
+}
+
+// crawlQueuedPeople 按 id 顺序采集 afterID 之后的用户, 队列只用于通知发现了新的 urlToken,
+// 所以中断时队列中的用户不会丢失, 下次从 peopleProgress 继续.
+func (zh *ZhiHu) crawlQueuedPeople(ctx context.Context, job *CollectJob, afterID uint64, finish chan struct{}) {
+	defer close(finish)
+	ds := zh.dataSource
+
+	ticker := time.NewTicker(zh.pauseDuration)
+	defer ticker.Stop()
+
+loop:
+	for {
+		ut, err := ds.GetURLToken(ctx, afterID)
+		if err == sql.ErrNoRows {
+			// 已经采集到最新的 urlToken, 等待发现新的 urlToken 或发现结束
+			select {
+			case <-ctx.Done():
+				break loop
+			case _, ok := <-job.queue:
+				if !ok {
+					break loop
+				}
+				continue loop
+			}
+		} else if err != nil {
+			logs.Error("error when get urlToken of pipeline: %s", err)
+			break loop
+		}
+
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-ticker.C:
+		}
+
+		if err := zh.crawlPeople(ctx, ut.ID, ut.URLToken); ctx.Err() != nil {
+			// 请求被中断, 下次从该用户继续
+			logs.Info("stop crawl queued people: %s", err)
+			break loop
+		} else if errors.Is(err, ErrGone) {
+			logs.Info("people gone, urlToken: %s", ut.URLToken)
+		} else if err != nil {
+			logs.Error("error when crawlPeople, urlToken: %s, err: %s",
+				ut.URLToken, err)
+		} else {
+			job.addProfile()
+		}
+		afterID = ut.ID
+	}
+
+	// ctx 取消后依然需要保存进度
+	pp := &PeopleProgress{
+		URLTokenID: afterID + 1,
+	}
+	if err := ds.InsertPeopleProgress(context.Background(), pp); err != nil {
+		logs.Error("error when insert peopleProgress: %s", err)
+	}
+
+	// 出错退出时继续接收通知直到发现结束, 避免 enqueue 一直等待
+	for range job.queue {
+	}
+}
+
+// enqueue 通知流水线有新插入的 urlToken, 队列满时等待; 不是流水线模式时什么也不做
+func (job *CollectJob) enqueue(ctx context.Context, urlTokens []*URLToken) {
+	if job == nil || job.queue == nil {
+		return
+	}
+	for _, ut := range urlTokens {
+		select {
+		case <-ctx.Done():
+			return
+		case job.queue <- ut:
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package modules
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/astaxie/beego/logs"
+)
+
+const (
+	recordMode = "record"
+	replayMode = "replay"
+)
+
+const fixtureFileSuffix = ".http"
+
+// newRecordTransport 根据 mode 创建录制或回放的 transport, maxBodySize 以上的响应不会被录制.
+func newRecordTransport(config *RecordConfig, maxBodySize int64, next http.RoundTripper) (http.RoundTripper, error) {
+	if config == nil || config.Dir == "" {
+		return nil, fmt.Errorf("invalid record config")
+	}
+
+	switch config.Mode {
+	case recordMode:
+		rt, err := NewRecordTransport(config.Dir, next)
+		if err != nil {
+			return nil, err
+		}
+		rt.maxBodySize = maxBodySize
+		return rt, nil
+	case replayMode:
+		return NewReplayTransport(config.Dir)
+	default:
+		return nil, fmt.Errorf("unexpected record mode: %s", config.Mode)
+	}
+}
+
+// NewRecordTransport 把经过的每一对请求/响应按顺序保存到 dir 中.
+// 每个 fixture 文件的第一行是 "METHOD URL", 之后是完整的响应.
+func NewRecordTransport(dir string, next http.RoundTripper) (*RecordTransport, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	// 接着已有的 fixture 编号, 避免覆盖
+	names, err := fixtureNames(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	rt := &RecordTransport{
+		dir:         dir,
+		maxBodySize: defaultMaxBodySize,
+		seq:         len(names),
+		next:        next,
+	}
+	return rt, nil
+}
+
+type RecordTransport struct {
+	dir         string
+	maxBodySize int64
+
+	mutex sync.Mutex
+	seq   int
+
+	next http.RoundTripper
+}
+
+func (rt *RecordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := rt.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// 先按限制读取 body, 避免 DumpResponse 读取过大的响应
+	ok, err := bufferBody(resp, rt.maxBodySize)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		logs.Warn("response body too large to record, url: %s, limit: %d", req.URL.String(), rt.maxBodySize)
+		return resp, nil
+	}
+
+	data, err := httputil.DumpResponse(resp, true)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "%s %s\n", req.Method, req.URL.String())
+	buf.Write(data)
+
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+
+	rt.seq++
+	path := filepath.Join(rt.dir, fmt.Sprintf("%06d%s", rt.seq, fixtureFileSuffix))
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
+
+// NewReplayTransport 读取 dir 中录制的 fixture, 同一个请求多次出现时按录制顺序依次返回.
+func NewReplayTransport(dir string) (*ReplayTransport, error) {
+	names, err := fixtureNames(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("not found fixture in: %s", dir)
+	}
+
+	rt := &ReplayTransport{
+		fixtures: make(map[string][][]byte),
+	}
+	for _, name := range names {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		idx := bytes.IndexByte(data, '\n')
+		if idx < 0 {
+			return nil, fmt.Errorf("invalid fixture: %s", name)
+		}
+		key := string(data[:idx])
+		rt.fixtures[key] = append(rt.fixtures[key], data[idx+1:])
+	}
+	return rt, nil
+}
+
+type ReplayTransport struct {
+	mutex    sync.Mutex
+	fixtures map[string][][]byte
+}
+
+func (rt *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := fmt.Sprintf("%s %s", req.Method, req.URL.String())
+
+	rt.mutex.Lock()
+	queue := rt.fixtures[key]
+	if len(queue) == 0 {
+		rt.mutex.Unlock()
+		return nil, fmt.Errorf("not found fixture for: %s", key)
+	}
+	data := queue[0]
+	rt.fixtures[key] = queue[1:]
+	rt.mutex.Unlock()
+
+	return http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), req)
+}
+
+func fixtureNames(dir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, info := range infos {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), fixtureFileSuffix) {
+			continue
+		}
+		names = append(names, info.Name())
+	}
+	sort.Strings(names)
+	return names, nil
+}
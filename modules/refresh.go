@@ -0,0 +1,75 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/astaxie/beego/logs"
+)
+
+const (
+	defaultRefreshAge = 30 * 24 * time.Hour
+	refreshBatchSize  = 100
+)
+
+// RefreshPeople 重新采集信息过期或从未采集过的用户, 粉丝多的用户优先.
+// 采集时间和失败记录保存在 urlToken 中, 所以中断后再次运行会从剩下的用户继续,
+// 本次运行中失败的用户在下次运行时重试, 已注销的用户不再刷新.
+func (zh *ZhiHu) RefreshPeople(ctx context.Context, job *CollectJob) {
+	ds := zh.dataSource
+	// 数据库中的时间只精确到秒, 本次运行失败的时间不会早于 start
+	start := time.Now().Truncate(time.Second)
+	before := start.Add(-job.refreshAge)
+
+	ticker := time.NewTicker(zh.pauseDuration)
+	defer ticker.Stop()
+
+	var refreshed, failed int
+	for {
+		urlTokens, err := ds.GetStalePeople(ctx, before, start, refreshBatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				logs.Error("error when get stale people: %s", err)
+			}
+			return
+		}
+		if len(urlTokens) == 0 {
+			logs.Info("refresh people finish, refreshed: %d, failed: %d", refreshed, failed)
+			return
+		}
+
+		for _, ut := range urlTokens {
+			select {
+			case <-ctx.Done():
+				logs.Info("stop refresh people")
+				return
+			case <-ticker.C:
+			}
+
+			err := zh.crawlPeople(ctx, ut.ID, ut.URLToken)
+			if ctx.Err() != nil {
+				logs.Info("stop refresh people: %s", err)
+				return
+			} else if err == nil {
+				refreshed++
+				job.addPage(1)
+				continue
+			}
+
+			gone := errors.Is(err, ErrGone)
+			if gone {
+				logs.Info("people gone, urlToken: %s", ut.URLToken)
+			} else {
+				logs.Error("error when refresh people, urlToken: %s, err: %s",
+					ut.URLToken, err)
+			}
+			failed++
+			// 没有记录失败时下一批还会返回该用户
+			if err := ds.MarkPeopleFailed(ctx, ut.ID, gone); err != nil {
+				logs.Error("error when mark people failed, urlToken: %s, err: %s", ut.URLToken, err)
+				return
+			}
+		}
+	}
+}
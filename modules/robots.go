@@ -0,0 +1,318 @@
+package modules
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"zhihu/utils"
+
+	"github.com/astaxie/beego/logs"
+)
+
+const (
+	defaultRobotsTTL = 24 * time.Hour
+	// robots.txt 返回 5xx 时最多重试的次数, 以及第一次重试前的等待时间
+	maxRobotsRetries          = 3
+	defaultRobotsRetryBackoff = 5 * time.Second
+)
+
+// newRobotsPolicy 按 userAgent 匹配规则, 它需要和请求使用的 User-Agent 相同.
+// client 不应该经过缓存.
+func newRobotsPolicy(config *RobotsConfig, userAgent string, client *http.Client, limiter *rateLimiter) (*robotsPolicy, error) {
+	if config == nil || !config.Enable {
+		return nil, fmt.Errorf("robots policy is not enabled")
+	}
+
+	ttl, err := parseDuration(config.TTL, defaultRobotsTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	rp := &robotsPolicy{
+		userAgent:    userAgent,
+		agent:        strings.ToLower(userAgent),
+		ttl:          ttl,
+		retryBackoff: defaultRobotsRetryBackoff,
+		client:       client,
+		limiter:      limiter,
+		hosts:        make(map[string]*robotsRules),
+	}
+	if rp.agent == "" {
+		rp.agent = strings.ToLower(utils.UserAgent)
+	}
+	return rp, nil
+}
+
+// robotsPolicy 按 host 缓存 robots.txt, 并把 Crawl-delay 交给 limiter
+type robotsPolicy struct {
+	userAgent    string
+	agent        string
+	ttl          time.Duration
+	retryBackoff time.Duration
+	client       *http.Client
+	limiter      *rateLimiter
+
+	// fetchMutex 保证同时只有一个请求在获取 robots.txt
+	fetchMutex sync.Mutex
+	mutex      sync.Mutex
+	hosts      map[string]*robotsRules
+}
+
+// check 在 robots.txt 不允许访问 rawURL 时返回 ErrDisallowed
+func (rp *robotsPolicy) check(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	rules, err := rp.rules(ctx, u)
+	if err != nil {
+		return err
+	}
+
+	path := u.EscapedPath()
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	if !rules.allowed(path) {
+		logs.Warn("refused by robots.txt: %s", rawURL)
+		return fmt.Errorf("%w, url: %s", ErrDisallowed, rawURL)
+	}
+	return nil
+}
+
+func (rp *robotsPolicy) rules(ctx context.Context, u *url.URL) (*robotsRules, error) {
+	key := u.Scheme + "://" + u.Host
+	if rules, ok := rp.cached(key); ok {
+		return rules, nil
+	}
+
+	rp.fetchMutex.Lock()
+	defer rp.fetchMutex.Unlock()
+
+	// 等待期间其他请求可能已经获取过了
+	if rules, ok := rp.cached(key); ok {
+		return rules, nil
+	}
+
+	rules, err := rp.fetchWithRetry(ctx, key+"/robots.txt")
+	if err != nil {
+		rp.mutex.Lock()
+		old, ok := rp.hosts[key]
+		rp.mutex.Unlock()
+		// 暂时无法获取时继续使用过期的规则
+		if ok && errors.Is(err, ErrServer) {
+			logs.Warn("use expired robots.txt of %s: %s", key, err)
+			return old, nil
+		}
+		return nil, err
+	}
+	if rules.crawlDelay > 0 {
+		logs.Info("crawl-delay of %s: %s", key, rules.crawlDelay)
+		rp.limiter.raise(rules.crawlDelay)
+	}
+
+	rp.mutex.Lock()
+	rp.hosts[key] = rules
+	rp.mutex.Unlock()
+	return rules, nil
+}
+
+func (rp *robotsPolicy) cached(key string) (*robotsRules, bool) {
+	rp.mutex.Lock()
+	defer rp.mutex.Unlock()
+
+	rules, ok := rp.hosts[key]
+	if !ok || time.Since(rules.fetchTime) >= rp.ttl {
+		return nil, false
+	}
+	return rules, true
+}
+
+// fetchWithRetry 在 robots.txt 返回 5xx 时等待后重试, 等待时间每次翻倍
+func (rp *robotsPolicy) fetchWithRetry(ctx context.Context, robotsURL string) (*robotsRules, error) {
+	backoff := rp.retryBackoff
+	for retries := 0; ; retries++ {
+		rules, err := rp.fetch(ctx, robotsURL)
+		if !errors.Is(err, ErrServer) || retries >= maxRobotsRetries {
+			return rules, err
+		}
+		logs.Warn("%s, retry after %s", err, backoff)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+func (rp *robotsPolicy) fetch(ctx context.Context, robotsURL string) (*robotsRules, error) {
+	req, err := newRequest(ctx, robotsURL, rp.userAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := rp.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch code := resp.StatusCode; {
+	case code >= 200 && code < 300:
+	case code >= 400 && code < 500:
+		// 没有 robots.txt 时允许访问所有路径
+		logs.Info("robots.txt not available, status: %d, url: %s", code, robotsURL)
+		return &robotsRules{fetchTime: time.Now()}, nil
+	case code >= 500:
+		// 服务端暂时出错, 不能当作禁止访问所有路径
+		return nil, fmt.Errorf("%w when get robots.txt, status: %d, url: %s", ErrServer, code, robotsURL)
+	default:
+		return nil, fmt.Errorf("unexpected status when get robots.txt, status: %d, url: %s", code, robotsURL)
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, defaultMaxBodySize))
+	if err != nil {
+		return nil, err
+	}
+	rules := parseRobots(data, rp.agent)
+	rules.fetchTime = time.Now()
+	return rules, nil
+}
+
+type robotsRules struct {
+	rules      []*robotsRule
+	crawlDelay time.Duration
+	fetchTime  time.Time
+}
+
+type robotsRule struct {
+	allow   bool
+	length  int
+	pattern *regexp.Regexp
+}
+
+// allowed 使用匹配长度最长的规则, 长度相同时 Allow 优先
+func (rr *robotsRules) allowed(path string) bool {
+	var matched *robotsRule
+	for _, rule := range rr.rules {
+		if !rule.pattern.MatchString(path) {
+			continue
+		}
+		if matched == nil || rule.length > matched.length ||
+			(rule.length == matched.length && rule.allow) {
+			matched = rule
+		}
+	}
+	return matched == nil || matched.allow
+}
+
+// parseRobots 解析 agent 对应的规则, 没有对应的组时使用 "*" 的规则
+func parseRobots(data []byte, agent string) *robotsRules {
+	type group struct {
+		agents []string
+		rules  *robotsRules
+	}
+
+	var groups []*group
+	var current *group
+	inAgents := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		idx := strings.Index(line, ":")
+		if idx < 0 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:idx]))
+		value := strings.TrimSpace(line[idx+1:])
+
+		switch key {
+		case "user-agent":
+			// 连续的 User-agent 属于同一组
+			if !inAgents {
+				current = &group{rules: &robotsRules{}}
+				groups = append(groups, current)
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			inAgents = true
+		case "allow", "disallow":
+			inAgents = false
+			if current == nil || value == "" {
+				// 空的 Disallow 表示允许所有路径
+				continue
+			}
+			current.rules.rules = append(current.rules.rules, &robotsRule{
+				allow:   key == "allow",
+				length:  len(value),
+				pattern: compileRobotsPattern(value),
+			})
+		case "crawl-delay":
+			inAgents = false
+			if current == nil {
+				continue
+			}
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+				current.rules.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		default:
+			inAgents = false
+		}
+	}
+
+	matchedRules := &robotsRules{}
+	defaultRules := &robotsRules{}
+	found := false
+	for _, g := range groups {
+		for _, a := range g.agents {
+			if a == "*" {
+				mergeRobotsRules(defaultRules, g.rules)
+			} else if agent != "" && strings.Contains(agent, a) {
+				mergeRobotsRules(matchedRules, g.rules)
+				found = true
+			}
+		}
+	}
+	if found {
+		return matchedRules
+	}
+	return defaultRules
+}
+
+func mergeRobotsRules(dst, src *robotsRules) {
+	dst.rules = append(dst.rules, src.rules...)
+	if src.crawlDelay > dst.crawlDelay {
+		dst.crawlDelay = src.crawlDelay
+	}
+}
+
+// compileRobotsPattern 支持 "*" 通配符和表示结尾的 "$"
+func compileRobotsPattern(value string) *regexp.Regexp {
+	anchored := strings.HasSuffix(value, "$")
+	value = strings.TrimSuffix(value, "$")
+
+	expr := "^" + strings.Replace(regexp.QuoteMeta(value), `\*`, ".*", -1)
+	if anchored {
+		expr += "$"
+	}
+	return regexp.MustCompile(expr)
+}
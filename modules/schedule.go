@@ -0,0 +1,189 @@
+package modules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// 错过运行时间后立即补跑一次
+	missedRun = "run"
+	// 错过运行时间后等待下一个运行时间
+	missedSkip = "skip"
+)
+
+// schedule 返回 t 之后的下一次运行时间, 返回零值表示立即运行
+type schedule interface {
+	next(t time.Time) time.Time
+}
+
+// parseSchedule 支持 5 个字段的 cron 表达式, "@every 1h", "@continuous"
+// 以及 "@hourly", "@daily", "@weekly", "@monthly".
+func parseSchedule(spec string) (schedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	switch spec {
+	case "@continuous":
+		return continuousSchedule{}, nil
+	case "@hourly":
+		spec = "0 * * * *"
+	case "@daily":
+		spec = "0 0 * * *"
+	case "@weekly":
+		spec = "0 0 * * 0"
+	case "@monthly":
+		spec = "0 0 1 * *"
+	}
+
+	if strings.HasPrefix(spec, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil {
+			return nil, err
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("invalid schedule: %s", spec)
+		}
+		return everySchedule{d}, nil
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule: %s, expect 5 fields", spec)
+	}
+
+	cs := &cronSchedule{}
+	var err error
+	if cs.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, err
+	}
+	if cs.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, err
+	}
+	if cs.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, err
+	}
+	if cs.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, err
+	}
+	if cs.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, err
+	}
+	// 0 和 7 都表示周日
+	if cs.dow&(1<<7) != 0 {
+		cs.dow |= 1
+	}
+	cs.domAny = fields[2] == "*"
+	cs.dowAny = fields[4] == "*"
+	return cs, nil
+}
+
+// parseCronField 解析 "*", "*/n", "a", "a-b", "a-b/n" 以及用逗号分隔的列表
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if idx := strings.Index(part, "/"); idx >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[idx+1:]); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid cron step: %s", part)
+			}
+			part = part[:idx]
+		}
+
+		start, end := min, max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			idx := strings.Index(part, "-")
+			var err1, err2 error
+			start, err1 = strconv.Atoi(part[:idx])
+			end, err2 = strconv.Atoi(part[idx+1:])
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("invalid cron range: %s", part)
+			}
+		default:
+			value, err := strconv.Atoi(part)
+			if err != nil {
+				return 0, fmt.Errorf("invalid cron value: %s", part)
+			}
+			start = value
+			if step == 1 {
+				end = value
+			}
+		}
+
+		if start < min || end > max || start > end {
+			return 0, fmt.Errorf("cron field out of range: %s, expect %d-%d", field, min, max)
+		}
+		for i := start; i <= end; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+	return bits, nil
+}
+
+type continuousSchedule struct{}
+
+func (continuousSchedule) next(t time.Time) time.Time {
+	return time.Time{}
+}
+
+type everySchedule struct {
+	interval time.Duration
+}
+
+func (es everySchedule) next(t time.Time) time.Time {
+	return t.Add(es.interval)
+}
+
+// cronSchedule 的每个字段用一个 bit 表示一个可以运行的值
+type cronSchedule struct {
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+
+	domAny bool
+	dowAny bool
+}
+
+// next 按月, 日, 时, 分依次查找, 最多向后查找 5 年
+func (cs *cronSchedule) next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if cs.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !cs.dayMatch(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if cs.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if cs.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	// 表达式永远不会匹配, 例如 2 月 30 日
+	return limit
+}
+
+// dayMatch 在日期和星期都有限制时满足其中一个即可, 与 cron 的行为一致
+func (cs *cronSchedule) dayMatch(t time.Time) bool {
+	dom := cs.dom&(1<<uint(t.Day())) != 0
+	dow := cs.dow&(1<<uint(t.Weekday())) != 0
+	if cs.domAny || cs.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
@@ -0,0 +1,178 @@
+package modules
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+
+	"github.com/astaxie/beego/logs"
+)
+
+// 搜索的类型, question 搜索的是综合结果中的问题和回答所属的问题
+const (
+	searchPeople   = "people"
+	searchTopic    = "topic"
+	searchQuestion = "question"
+)
+
+// 搜索结果中匹配的关键词会被 <em> 标签包围
+var highlightReplacer = strings.NewReplacer("<em>", "", "</em>", "")
+
+type PagingSearch struct {
+	Paging *Paging         `json:"paging"`
+	Data   []*SearchResult `json:"data"`
+}
+
+type SearchResult struct {
+	Type   string        `json:"type"`
+	Object *SearchObject `json:"object"`
+}
+
+// SearchObject 是搜索到的用户, 话题, 问题或回答, 用户的 id 是字符串, 问题的 id 是数字
+type SearchObject struct {
+	ID       json.RawMessage `json:"id"`
+	Type     string          `json:"type"`
+	URLToken string          `json:"url_token"`
+	Name     string          `json:"name"`
+	Title    string          `json:"title"`
+	Question *SearchObject   `json:"question"`
+}
+
+func (so *SearchObject) id() string {
+	return strings.Trim(string(so.ID), `"`)
+}
+
+// title 返回去掉高亮的标题, 问题的标题可能在 name 中
+func (so *SearchObject) title() string {
+	title := so.Title
+	if title == "" {
+		title = so.Name
+	}
+	return highlightReplacer.Replace(title)
+}
+
+// parseSearch 解析一页搜索结果, 返回其中的用户, 话题和问题, 其他类型会被跳过
+func parseSearch(data []byte) (*PagingSearch, []*URLToken, []*TopicID, []*Question, error) {
+	ps := &PagingSearch{}
+	if err := json.Unmarshal(data, ps); err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	var urlTokens []*URLToken
+	var topicsID []*TopicID
+	var questions []*Question
+	for _, result := range ps.Data {
+		object := result.Object
+		if object == nil {
+			continue
+		}
+		if object.Type == "answer" {
+			object = object.Question
+			if object == nil {
+				continue
+			}
+		}
+
+		switch object.Type {
+		case "people":
+			if object.URLToken != "" {
+				urlTokens = append(urlTokens, &URLToken{URLToken: object.URLToken})
+			}
+		case "topic":
+			topicsID = append(topicsID, &TopicID{TopicID: object.id(), Name: object.title()})
+		case "question":
+			questions = append(questions, &Question{QuestionID: object.id(), Title: object.title()})
+		}
+	}
+	return ps, urlTokens, topicsID, questions, nil
+}
+
+// saveSearch 把搜索到的用户作为种子插入 urlToken 表, 深度为 0
+func (zh *ZhiHu) saveSearch(ctx context.Context, data []byte) (*Paging, int, error) {
+	ps, urlTokens, topicsID, questions, err := parseSearch(data)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if _, err := zh.dataSource.InsertURLTokens(ctx, urlTokens); err != nil {
+		return nil, 0, err
+	}
+	if err := zh.dataSource.InsertTopicsID(ctx, topicsID); err != nil {
+		return nil, 0, err
+	}
+	if err := zh.dataSource.InsertQuestions(ctx, questions); err != nil {
+		return nil, 0, err
+	}
+	return ps.Paging, len(ps.Data), nil
+}
+
+// CollectSearch 依次搜索 Keywords 中的每个关键词, 每种类型最多 MaxPages 页.
+// 全部搜索完后下次运行从第一个关键词重新开始.
+func (zh *ZhiHu) CollectSearch(ctx context.Context, job *CollectJob) {
+	ds := zh.dataSource
+	keywords := job.config.Keywords
+	searchTypes := job.config.SearchTypes
+	if len(keywords) == 0 {
+		logs.Warn("no keywords to search, job: %s", job.Name())
+		return
+	}
+
+	// 配置修改后找不到保存的关键词或类型时从头开始
+	var keywordIndex, typeIndex int
+	var startURL string
+	sp, err := ds.GetSearchProgress(ctx)
+	if err != nil && err != sql.ErrNoRows {
+		logs.Error("error when get searchProgress: %s", err)
+		return
+	} else if err == nil && sp.Keyword != "" {
+		for i, keyword := range keywords {
+			if keyword != sp.Keyword {
+				continue
+			}
+			for j, searchType := range searchTypes {
+				if searchType == sp.SearchType {
+					keywordIndex, typeIndex = i, j
+					startURL = sp.NextSearchURL
+				}
+			}
+		}
+		logs.Info("load searchProgress success")
+	}
+
+loop:
+	for ; keywordIndex < len(keywords); keywordIndex++ {
+		keyword := keywords[keywordIndex]
+		for ; typeIndex < len(searchTypes); typeIndex++ {
+			searchType := searchTypes[typeIndex]
+			if startURL == "" {
+				startURL = zh.api.SearchURL(searchType, keyword)
+			}
+			nextURL, err := zh.continuePaging(ctx, job, startURL, job.config.MaxPages, zh.saveSearch)
+			// 这种类型已经搜索完时 err 为 nil, 即使 ctx 已经取消也继续下一种类型, 避免下次重新搜索
+			if err != nil && ctx.Err() != nil {
+				// 下次从这一页继续
+				logs.Info("stop search: %s", err)
+				startURL = nextURL
+				break loop
+			} else if err != nil {
+				logs.Error("error when search, keyword: %s, type: %s, err: %s", keyword, searchType, err)
+			}
+			startURL = ""
+		}
+		typeIndex = 0
+	}
+
+	// ctx 取消后依然需要保存进度
+	ctx = context.Background()
+
+	sp = &SearchProgress{}
+	if keywordIndex < len(keywords) {
+		sp.Keyword = keywords[keywordIndex]
+		sp.SearchType = searchTypes[typeIndex]
+		sp.NextSearchURL = startURL
+	}
+	if err := ds.InsertSearchProgress(ctx, sp); err != nil {
+		logs.Error("error when insert searchProgress: %s", err)
+	}
+}
@@ -1,14 +1,31 @@
 package modules
 
+import "time"
+
 type URLToken struct {
 	ID       uint64
 	URLToken string
+	// Depth 是从 OwnURLToken 开始的 BFS 深度
+	Depth int
+	// Score 越大越先展开, 只在配置了 priority 时计算
+	Score float64
 }
 
 func (ut *URLToken) ToScan() []interface{} {
 	var fields []interface{}
 	fields = append(fields, &ut.ID)
 	fields = append(fields, &ut.URLToken)
+	fields = append(fields, &ut.Depth)
+	fields = append(fields, &ut.Score)
+	return fields
+}
+
+func (ut *URLToken) ToInsert() []interface{} {
+	var fields []interface{}
+
+	fields = append(fields, ut.URLToken)
+	fields = append(fields, ut.Depth)
+	fields = append(fields, ut.Score)
 	return fields
 }
 
@@ -92,6 +109,11 @@ type TopicTable struct {
 	TopicID       uint64
 	FollowerCount uint64
 	QuestionCount uint64
+	// 以下字段只有通过接口采集时才有
+	Name               string
+	Introduction       string
+	AvatarURL          string
+	BestAnswerersCount uint64
 }
 
 func (tt *TopicTable) ToInsert() []interface{} {
@@ -100,6 +122,10 @@ func (tt *TopicTable) ToInsert() []interface{} {
 	fields = append(fields, tt.TopicID)
 	fields = append(fields, tt.FollowerCount)
 	fields = append(fields, tt.QuestionCount)
+	fields = append(fields, tt.Name)
+	fields = append(fields, tt.Introduction)
+	fields = append(fields, tt.AvatarURL)
+	fields = append(fields, tt.BestAnswerersCount)
 	return fields
 }
 
@@ -153,6 +179,8 @@ type People struct {
 	Locations     []Location   `json:"locations"`
 	Educations    []Education  `json:"educations"`
 	Employments   []Employment `json:"employments"`
+	// CrawlTime 为零值时使用插入的时间
+	CrawlTime time.Time
 }
 
 func (p *People) ToInsert() []interface{} {
@@ -198,6 +226,12 @@ func (p *People) ToInsert() []interface{} {
 		job = p.Employments[0].Job.Name
 	}
 	fields = append(fields, company, job)
+
+	crawlTime := p.CrawlTime
+	if crawlTime.IsZero() {
+		crawlTime = time.Now()
+	}
+	fields = append(fields, crawlTime)
 	return fields
 }
 
@@ -236,3 +270,445 @@ func (pp *PeopleProgress) ToScan() []interface{} {
 	fields = append(fields, &pp.URLTokenID)
 	return fields
 }
+
+type JobRun struct {
+	ID        uint64
+	Name      string
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+func (jr *JobRun) ToScan() []interface{} {
+	var fields []interface{}
+
+	fields = append(fields, &jr.ID)
+	fields = append(fields, &jr.Name)
+	fields = append(fields, &jr.StartTime)
+	fields = append(fields, &jr.EndTime)
+	return fields
+}
+
+func (jr *JobRun) ToInsert() []interface{} {
+	var fields []interface{}
+
+	fields = append(fields, jr.Name)
+	fields = append(fields, jr.StartTime)
+	fields = append(fields, jr.EndTime)
+	return fields
+}
+
+// TopicIDRoot 表示话题可以从根话题 RootTopicID 经过 Depth 层到达
+type TopicIDRoot struct {
+	ID          uint64
+	TopicID     string
+	RootTopicID string
+	Depth       int
+}
+
+func (tir *TopicIDRoot) ToScan() []interface{} {
+	var fields []interface{}
+
+	fields = append(fields, &tir.ID)
+	fields = append(fields, &tir.TopicID)
+	fields = append(fields, &tir.RootTopicID)
+	fields = append(fields, &tir.Depth)
+	return fields
+}
+
+func (tir *TopicIDRoot) ToInsert() []interface{} {
+	var fields []interface{}
+
+	fields = append(fields, tir.TopicID)
+	fields = append(fields, tir.RootTopicID)
+	fields = append(fields, tir.Depth)
+	return fields
+}
+
+type Question struct {
+	ID         uint64
+	QuestionID string
+	Title      string
+	// 从回答中得到的问题没有以下字段
+	AnswerCount   uint64
+	FollowerCount uint64
+	CreatedTime   time.Time
+}
+
+func (q *Question) ToScan() []interface{} {
+	var fields []interface{}
+
+	fields = append(fields, &q.ID)
+	fields = append(fields, &q.QuestionID)
+	fields = append(fields, &q.Title)
+	return fields
+}
+
+func (q *Question) ToInsert() []interface{} {
+	var fields []interface{}
+
+	fields = append(fields, q.QuestionID)
+	fields = append(fields, q.Title)
+	fields = append(fields, q.AnswerCount)
+	fields = append(fields, q.FollowerCount)
+	if q.CreatedTime.IsZero() {
+		fields = append(fields, nil)
+	} else {
+		fields = append(fields, q.CreatedTime)
+	}
+	return fields
+}
+
+// TopicQuestion 表示问题出现在话题的 Feed 中
+type TopicQuestion struct {
+	ID         uint64
+	TopicID    string
+	QuestionID string
+	Feed       string
+}
+
+func (tq *TopicQuestion) ToInsert() []interface{} {
+	var fields []interface{}
+
+	fields = append(fields, tq.TopicID)
+	fields = append(fields, tq.QuestionID)
+	fields = append(fields, tq.Feed)
+	return fields
+}
+
+// TopicQuestionProgress 记录正在采集问题的话题, Feed 和下一页的地址
+type TopicQuestionProgress struct {
+	ID              uint64
+	TopicID         uint64
+	Feed            string
+	NextQuestionURL string
+}
+
+func (tqp *TopicQuestionProgress) ToScan() []interface{} {
+	var fields []interface{}
+
+	fields = append(fields, &tqp.ID)
+	fields = append(fields, &tqp.TopicID)
+	fields = append(fields, &tqp.Feed)
+	fields = append(fields, &tqp.NextQuestionURL)
+	return fields
+}
+
+func (tqp *TopicQuestionProgress) ToInsert() []interface{} {
+	var fields []interface{}
+
+	fields = append(fields, tqp.TopicID)
+	fields = append(fields, tqp.Feed)
+	fields = append(fields, tqp.NextQuestionURL)
+	return fields
+}
+
+// AnswerTable 有重名的结构, 所以使用了 Table 后缀
+type AnswerTable struct {
+	ID             uint64
+	AnswerID       string
+	QuestionID     string
+	AuthorURLToken string
+	// URLTokenID 是作者在 urlToken 表中的 id, 采集问题的回答时为 0
+	URLTokenID   uint64
+	VoteupCount  uint64
+	CommentCount uint64
+	CreatedTime  time.Time
+	UpdatedTime  time.Time
+	Content      string
+}
+
+func (at *AnswerTable) ToScan() []interface{} {
+	var fields []interface{}
+
+	fields = append(fields, &at.ID)
+	fields = append(fields, &at.AnswerID)
+	return fields
+}
+
+func (at *AnswerTable) ToInsert() []interface{} {
+	var fields []interface{}
+
+	fields = append(fields, at.AnswerID)
+	fields = append(fields, at.QuestionID)
+	fields = append(fields, at.AuthorURLToken)
+	fields = append(fields, at.URLTokenID)
+	fields = append(fields, at.VoteupCount)
+	fields = append(fields, at.CommentCount)
+	fields = append(fields, at.CreatedTime)
+	fields = append(fields, at.UpdatedTime)
+	fields = append(fields, at.Content)
+	return fields
+}
+
+// AnswerProgress 记录正在采集回答的问题和下一页的地址
+type AnswerProgress struct {
+	ID            uint64
+	QuestionID    uint64
+	NextAnswerURL string
+}
+
+func (ap *AnswerProgress) ToScan() []interface{} {
+	var fields []interface{}
+
+	fields = append(fields, &ap.ID)
+	fields = append(fields, &ap.QuestionID)
+	fields = append(fields, &ap.NextAnswerURL)
+	return fields
+}
+
+func (ap *AnswerProgress) ToInsert() []interface{} {
+	var fields []interface{}
+
+	fields = append(fields, ap.QuestionID)
+	fields = append(fields, ap.NextAnswerURL)
+	return fields
+}
+
+// UserAnswerProgress 记录正在采集回答的用户和下一页的地址
+type UserAnswerProgress struct {
+	ID            uint64
+	URLTokenID    uint64
+	NextAnswerURL string
+}
+
+func (uap *UserAnswerProgress) ToScan() []interface{} {
+	var fields []interface{}
+
+	fields = append(fields, &uap.ID)
+	fields = append(fields, &uap.URLTokenID)
+	fields = append(fields, &uap.NextAnswerURL)
+	return fields
+}
+
+func (uap *UserAnswerProgress) ToInsert() []interface{} {
+	var fields []interface{}
+
+	fields = append(fields, uap.URLTokenID)
+	fields = append(fields, uap.NextAnswerURL)
+	return fields
+}
+
+type ArticleTable struct {
+	ID             uint64
+	ArticleID      string
+	Title          string
+	ColumnID       string
+	AuthorURLToken string
+	// URLTokenID 是作者在 urlToken 表中的 id, 按专栏采集时为 0
+	URLTokenID   uint64
+	VoteupCount  uint64
+	CommentCount uint64
+	CreatedTime  time.Time
+	UpdatedTime  time.Time
+}
+
+func (at *ArticleTable) ToScan() []interface{} {
+	var fields []interface{}
+
+	fields = append(fields, &at.ID)
+	fields = append(fields, &at.ArticleID)
+	return fields
+}
+
+func (at *ArticleTable) ToInsert() []interface{} {
+	var fields []interface{}
+
+	fields = append(fields, at.ArticleID)
+	fields = append(fields, at.Title)
+	fields = append(fields, at.ColumnID)
+	fields = append(fields, at.AuthorURLToken)
+	fields = append(fields, at.URLTokenID)
+	fields = append(fields, at.VoteupCount)
+	fields = append(fields, at.CommentCount)
+	fields = append(fields, at.CreatedTime)
+	fields = append(fields, at.UpdatedTime)
+	return fields
+}
+
+type ColumnTable struct {
+	ID             uint64
+	ColumnID       string
+	Title          string
+	Intro          string
+	AuthorURLToken string
+	ArticlesCount  uint64
+	Followers      uint64
+}
+
+func (ct *ColumnTable) ToScan() []interface{} {
+	var fields []interface{}
+
+	fields = append(fields, &ct.ID)
+	fields = append(fields, &ct.ColumnID)
+	fields = append(fields, &ct.Title)
+	return fields
+}
+
+func (ct *ColumnTable) ToInsert() []interface{} {
+	var fields []interface{}
+
+	fields = append(fields, ct.ColumnID)
+	fields = append(fields, ct.Title)
+	fields = append(fields, ct.Intro)
+	fields = append(fields, ct.AuthorURLToken)
+	fields = append(fields, ct.ArticlesCount)
+	fields = append(fields, ct.Followers)
+	return fields
+}
+
+// UserArticleProgress 记录正在采集的用户和文章, 专栏下一页的地址
+type UserArticleProgress struct {
+	ID             uint64
+	URLTokenID     uint64
+	NextArticleURL string
+	NextColumnURL  string
+}
+
+func (uap *UserArticleProgress) ToScan() []interface{} {
+	var fields []interface{}
+
+	fields = append(fields, &uap.ID)
+	fields = append(fields, &uap.URLTokenID)
+	fields = append(fields, &uap.NextArticleURL)
+	fields = append(fields, &uap.NextColumnURL)
+	return fields
+}
+
+func (uap *UserArticleProgress) ToInsert() []interface{} {
+	var fields []interface{}
+
+	fields = append(fields, uap.URLTokenID)
+	fields = append(fields, uap.NextArticleURL)
+	fields = append(fields, uap.NextColumnURL)
+	return fields
+}
+
+// ColumnArticleProgress 记录正在采集文章的专栏和下一页的地址
+type ColumnArticleProgress struct {
+	ID             uint64
+	ColumnID       uint64
+	NextArticleURL string
+}
+
+func (cp *ColumnArticleProgress) ToScan() []interface{} {
+	var fields []interface{}
+
+	fields = append(fields, &cp.ID)
+	fields = append(fields, &cp.ColumnID)
+	fields = append(fields, &cp.NextArticleURL)
+	return fields
+}
+
+func (cp *ColumnArticleProgress) ToInsert() []interface{} {
+	var fields []interface{}
+
+	fields = append(fields, cp.ColumnID)
+	fields = append(fields, cp.NextArticleURL)
+	return fields
+}
+
+// CommentTable 有重名的结构, 所以使用了 Table 后缀
+type CommentTable struct {
+	ID        uint64
+	CommentID string
+	// ResourceType 是 answer 或 article, ResourceID 是回答或文章的 id
+	ResourceType string
+	ResourceID   string
+	// RootCommentID 是子评论所属的根评论, 根评论为空
+	RootCommentID   string
+	AuthorURLToken  string
+	ReplyToURLToken string
+	Content         string
+	LikeCount       uint64
+	CreatedTime     time.Time
+}
+
+func (ct *CommentTable) ToInsert() []interface{} {
+	var fields []interface{}
+
+	fields = append(fields, ct.CommentID)
+	fields = append(fields, ct.ResourceType)
+	fields = append(fields, ct.ResourceID)
+	fields = append(fields, ct.RootCommentID)
+	fields = append(fields, ct.AuthorURLToken)
+	fields = append(fields, ct.ReplyToURLToken)
+	fields = append(fields, ct.Content)
+	fields = append(fields, ct.LikeCount)
+	fields = append(fields, ct.CreatedTime)
+	return fields
+}
+
+// CommentProgress 记录每种资源正在采集评论的回答或文章和下一页的地址
+type CommentProgress struct {
+	ID             uint64
+	ResourceType   string
+	ResourceID     uint64
+	NextCommentURL string
+}
+
+func (cp *CommentProgress) ToScan() []interface{} {
+	var fields []interface{}
+
+	fields = append(fields, &cp.ID)
+	fields = append(fields, &cp.ResourceType)
+	fields = append(fields, &cp.ResourceID)
+	fields = append(fields, &cp.NextCommentURL)
+	return fields
+}
+
+func (cp *CommentProgress) ToInsert() []interface{} {
+	var fields []interface{}
+
+	fields = append(fields, cp.ResourceType)
+	fields = append(fields, cp.ResourceID)
+	fields = append(fields, cp.NextCommentURL)
+	return fields
+}
+
+// HotListTable 是一次热榜快照中的一个问题, 同一次快照的 SnapshotTime 相同
+type HotListTable struct {
+	ID           uint64
+	SnapshotTime time.Time
+	Rank         int
+	QuestionID   string
+	Title        string
+	Heat         uint64
+}
+
+func (ht *HotListTable) ToInsert() []interface{} {
+	var fields []interface{}
+
+	fields = append(fields, ht.SnapshotTime)
+	fields = append(fields, ht.Rank)
+	fields = append(fields, ht.QuestionID)
+	fields = append(fields, ht.Title)
+	fields = append(fields, ht.Heat)
+	return fields
+}
+
+// SearchProgress 记录正在搜索的关键词, 类型和下一页的地址, Keyword 为空表示从头开始
+type SearchProgress struct {
+	ID            uint64
+	Keyword       string
+	SearchType    string
+	NextSearchURL string
+}
+
+func (sp *SearchProgress) ToScan() []interface{} {
+	var fields []interface{}
+
+	fields = append(fields, &sp.ID)
+	fields = append(fields, &sp.Keyword)
+	fields = append(fields, &sp.SearchType)
+	fields = append(fields, &sp.NextSearchURL)
+	return fields
+}
+
+func (sp *SearchProgress) ToInsert() []interface{} {
+	var fields []interface{}
+
+	fields = append(fields, sp.Keyword)
+	fields = append(fields, sp.SearchType)
+	fields = append(fields, sp.NextSearchURL)
+	return fields
+}
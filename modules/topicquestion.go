@@ -0,0 +1,145 @@
+package modules
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// 话题下的问题列表
+const (
+	topicFeedTop      = "top_activity"
+	topicFeedEssence  = "essence"
+	topicFeedTimeline = "timeline_question"
+)
+
+type PagingTopicFeed struct {
+	Paging *Paging      `json:"paging"`
+	Data   []*TopicFeed `json:"data"`
+}
+
+type TopicFeed struct {
+	Target *FeedTarget `json:"target"`
+}
+
+// FeedTarget 是列表中的问题, 回答或文章, 回答中的 Question 是它所属的问题
+type FeedTarget struct {
+	QuestionDetail
+	Type     string          `json:"type"`
+	Question *QuestionDetail `json:"question"`
+}
+
+type QuestionDetail struct {
+	ID            uint64 `json:"id"`
+	Title         string `json:"title"`
+	AnswerCount   uint64 `json:"answer_count"`
+	FollowerCount uint64 `json:"follower_count"`
+	Created       int64  `json:"created"`
+}
+
+func (qd *QuestionDetail) toTable() *Question {
+	q := &Question{
+		QuestionID:    strconv.FormatUint(qd.ID, 10),
+		Title:         qd.Title,
+		AnswerCount:   qd.AnswerCount,
+		FollowerCount: qd.FollowerCount,
+	}
+	if qd.Created > 0 {
+		q.CreatedTime = time.Unix(qd.Created, 0)
+	}
+	return q
+}
+
+// parseTopicFeed 解析一页话题列表, 返回其中的问题和回答所属的问题, 文章会被跳过
+func parseTopicFeed(data []byte) (*PagingTopicFeed, []*Question, error) {
+	pf := &PagingTopicFeed{}
+	if err := json.Unmarshal(data, pf); err != nil {
+		return nil, nil, err
+	}
+
+	var questions []*Question
+	for _, feed := range pf.Data {
+		if feed.Target == nil {
+			continue
+		}
+		switch feed.Target.Type {
+		case "question":
+			questions = append(questions, feed.Target.QuestionDetail.toTable())
+		case "answer":
+			if feed.Target.Question != nil {
+				questions = append(questions, feed.Target.Question.toTable())
+			}
+		}
+	}
+	return pf, questions, nil
+}
+
+// topicQuestionSaver 返回保存一页问题以及问题和话题关系的 pageHandler
+func (zh *ZhiHu) topicQuestionSaver(topicID, feed string) pageHandler {
+	return func(ctx context.Context, data []byte) (*Paging, int, error) {
+		pf, questions, err := parseTopicFeed(data)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		var topicQuestions []*TopicQuestion
+		for _, question := range questions {
+			topicQuestions = append(topicQuestions, &TopicQuestion{
+				TopicID:    topicID,
+				QuestionID: question.QuestionID,
+				Feed:       feed,
+			})
+		}
+		if err := zh.dataSource.InsertQuestions(ctx, questions); err != nil {
+			return nil, 0, err
+		}
+		if err := zh.dataSource.InsertTopicQuestions(ctx, topicQuestions); err != nil {
+			return nil, 0, err
+		}
+		// 返回整页的数量, 过滤掉的文章不能当作最后一页
+		return pf.Paging, len(pf.Data), nil
+	}
+}
+
+// CollectTopicQuestions 依次采集 topicID 表中每个话题 TopicFeeds 列表中的问题, 每个列表最多 MaxPages 页
+func (zh *ZhiHu) CollectTopicQuestions(ctx context.Context, job *CollectJob) {
+	ds := zh.dataSource
+	feeds := job.config.TopicFeeds
+
+	zh.walk(ctx, job, &resumableWalk{
+		name: "topic questions",
+		// 配置修改后找不到保存的列表时从第一个开始
+		loadProgress: func(ctx context.Context) (uint64, int, string, error) {
+			tqp, err := ds.GetTopicQuestionProgress(ctx)
+			for i, feed := range feeds {
+				if feed == tqp.Feed {
+					return tqp.TopicID, i, tqp.NextQuestionURL, err
+				}
+			}
+			return tqp.TopicID, 0, "", err
+		},
+		saveProgress: func(ctx context.Context, id uint64, list int, nextURL string) error {
+			tqp := &TopicQuestionProgress{
+				TopicID:         id,
+				NextQuestionURL: nextURL,
+			}
+			if list < len(feeds) {
+				tqp.Feed = feeds[list]
+			}
+			return ds.InsertTopicQuestionProgress(ctx, tqp)
+		},
+		offset: ds.GetTopicIDOffset,
+		get: func(ctx context.Context, offset uint64) (*walkItem, error) {
+			ti, err := ds.GetTopicID(ctx, offset)
+			if err != nil {
+				return nil, err
+			}
+			item := &walkItem{id: ti.ID, name: ti.TopicID}
+			for _, feed := range feeds {
+				item.lists = append(item.lists, walkList{zh.api.TopicFeedURL(ti.TopicID, feed), zh.topicQuestionSaver(ti.TopicID, feed)})
+			}
+			return item, nil
+		},
+	})
+}
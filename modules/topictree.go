@@ -0,0 +1,131 @@
+package modules
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/astaxie/beego/logs"
+)
+
+// collectTopicSubtree 从多个根话题开始按 BFS 采集子话题, 每个话题记录可以到达它的根话题.
+// 展开完成或已经不存在的话题会被标记, 中断或出错后重新展开正在展开的话题.
+func (zh *ZhiHu) collectTopicSubtree(ctx context.Context, job *CollectJob) {
+	ds := zh.dataSource
+
+	exclude := make(map[string]bool)
+	for _, id := range job.config.ExcludeTopicIDs {
+		exclude[id] = true
+	}
+
+	var roots []*TopicIDRoot
+	var rootTopicIDs []string
+	var topicsID []*TopicID
+	for _, id := range job.config.RootTopicIDs {
+		if exclude[id] {
+			logs.Warn("root topic %s is excluded", id)
+			continue
+		}
+		topicsID = append(topicsID, &TopicID{
+			TopicID: id,
+			Name:    "根话题",
+		})
+		roots = append(roots, &TopicIDRoot{
+			TopicID:     id,
+			RootTopicID: id,
+		})
+		rootTopicIDs = append(rootTopicIDs, id)
+	}
+	if len(roots) == 0 {
+		logs.Error("no root topic to collect, job: %s", job.Name())
+		return
+	}
+	if err := ds.InsertTopicsID(ctx, topicsID); err != nil {
+		logs.Error("error when insert root topics: %s", err)
+		return
+	}
+	if err := ds.InsertTopicIDRoots(ctx, roots); err != nil {
+		logs.Error("error when insert root topics: %s", err)
+		return
+	}
+
+	for {
+		tir, err := ds.GetTopicIDRootFrontier(ctx, rootTopicIDs, job.config.MaxDepth)
+		if err == sql.ErrNoRows {
+			logs.Info("collect topic subtree finish, roots: %v", rootTopicIDs)
+			return
+		} else if err != nil {
+			if ctx.Err() == nil {
+				logs.Error("error when get topic frontier: %s", err)
+			}
+			return
+		}
+
+		if err := zh.expandTopic(ctx, job, tir, exclude); errors.Is(err, ErrGone) {
+			// 话题已经不存在, 标记后跳过
+			logs.Warn("topic gone: %s", tir.TopicID)
+		} else if err != nil {
+			if ctx.Err() != nil {
+				logs.Info("stop collect topic subtree, topic: %s", tir.TopicID)
+				return
+			}
+			// 不标记出错的话题, 下次运行时重新展开
+			logs.Error("error when expand topic: %s, err: %s", tir.TopicID, err)
+			return
+		}
+
+		if err := ds.MarkTopicIDRootExpanded(ctx, tir.ID); err != nil {
+			logs.Error("error when mark topic expanded: %s", err)
+			return
+		}
+	}
+}
+
+// expandTopic 保存 tir 的所有子话题, 子话题和 tir 属于同一个根话题
+func (zh *ZhiHu) expandTopic(ctx context.Context, job *CollectJob, tir *TopicIDRoot, exclude map[string]bool) error {
+	ticker := time.NewTicker(zh.pauseDuration)
+	defer ticker.Stop()
+
+	for nextURL := zh.api.TopicIDURL(tir.TopicID); ; {
+		pt, err := zh.getTopicID(ctx, nextURL)
+		if err != nil {
+			return err
+		}
+		if len(pt.Data) == 0 {
+			return nil
+		}
+
+		var topicsID []*TopicID
+		var children []*TopicIDRoot
+		for _, topic := range pt.Data {
+			if exclude[topic.ID] {
+				continue
+			}
+			topicsID = append(topicsID, &TopicID{
+				TopicID: topic.ID,
+				Name:    topic.Name,
+			})
+			children = append(children, &TopicIDRoot{
+				TopicID:     topic.ID,
+				RootTopicID: tir.RootTopicID,
+				Depth:       tir.Depth + 1,
+			})
+		}
+
+		if err := zh.dataSource.InsertTopicsID(ctx, topicsID); err != nil {
+			return err
+		}
+		if err := zh.dataSource.InsertTopicIDRoots(ctx, children); err != nil {
+			return err
+		}
+		job.addPage(len(pt.Data))
+		nextURL = pt.Paging.Next
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package modules
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultTimeout               = 30 * time.Second
+	defaultDialTimeout           = 10 * time.Second
+	defaultTLSHandshakeTimeout   = 10 * time.Second
+	defaultResponseHeaderTimeout = 20 * time.Second
+	defaultIdleConnTimeout       = 90 * time.Second
+
+	defaultMaxIdleConns        = 100
+	defaultMaxIdleConnsPerHost = 10
+	defaultMaxBodySize         = 10 << 20
+)
+
+// newClient 创建带超时的 client, 所有采集请求 (包括 html 页面) 都使用它.
+func newClient(config *HTTPConfig) (*http.Client, error) {
+	timeout, err := parseDuration(config.Timeout, defaultTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	transport, err := newTransport(config)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+	}
+	return client, nil
+}
+
+// newRobotsClient 创建请求 robots.txt 的 client, 不经过缓存, 这样规则按 TTL 更新
+func newRobotsClient(config *HTTPConfig) (*http.Client, error) {
+	c := *config
+	c.Cache = nil
+	return newClient(&c)
+}
+
+// newTransport 根据配置在底层 transport 外层包装录制/回放, 缓存等功能.
+func newTransport(config *HTTPConfig) (http.RoundTripper, error) {
+	transport, err := newBaseTransport(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// 录制/回放最靠近网络, 这样缓存命中的请求不会被录制
+	limit := maxBodySize(config)
+	if config.Record != nil {
+		rt, err := newRecordTransport(config.Record, limit, transport)
+		if err != nil {
+			return nil, err
+		}
+		transport = rt
+	}
+	if config.Cache != nil {
+		ct, err := newCacheTransport(config.Cache, limit, transport)
+		if err != nil {
+			return nil, err
+		}
+		transport = ct
+	}
+	return transport, nil
+}
+
+func newBaseTransport(config *HTTPConfig) (http.RoundTripper, error) {
+	dialTimeout, err := parseDuration(config.DialTimeout, defaultDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	tlsHandshakeTimeout, err := parseDuration(config.TLSHandshakeTimeout, defaultTLSHandshakeTimeout)
+	if err != nil {
+		return nil, err
+	}
+	responseHeaderTimeout, err := parseDuration(config.ResponseHeaderTimeout, defaultResponseHeaderTimeout)
+	if err != nil {
+		return nil, err
+	}
+	idleConnTimeout, err := parseDuration(config.IdleConnTimeout, defaultIdleConnTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	maxIdleConns := config.MaxIdleConns
+	if maxIdleConns <= 0 {
+		maxIdleConns = defaultMaxIdleConns
+	}
+	maxIdleConnsPerHost := config.MaxIdleConnsPerHost
+	if maxIdleConnsPerHost <= 0 {
+		maxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   dialTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   tlsHandshakeTimeout,
+		ResponseHeaderTimeout: responseHeaderTimeout,
+		IdleConnTimeout:       idleConnTimeout,
+		MaxIdleConns:          maxIdleConns,
+		MaxIdleConnsPerHost:   maxIdleConnsPerHost,
+		ExpectContinueTimeout: time.Second,
+	}
+	return transport, nil
+}
+
+func maxBodySize(config *HTTPConfig) int64 {
+	if config.MaxBodySize <= 0 {
+		return defaultMaxBodySize
+	}
+	return config.MaxBodySize
+}
+
+// bufferBody 最多读取 limit+1 字节的响应内容, 读取的内容会放回 resp.Body.
+// 超出 limit 时返回 false, 这样的响应不能保存, 剩余内容留给调用者按限制处理.
+func bufferBody(resp *http.Response, limit int64) (bool, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		resp.Body.Close()
+		return false, err
+	}
+
+	if int64(len(data)) > limit {
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(data), resp.Body), resp.Body}
+		return false, nil
+	}
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+	return true, nil
+}
+
+// parseDuration 在 value 为空时返回默认值
+func parseDuration(value string, defaultDur time.Duration) (time.Duration, error) {
+	if value == "" {
+		return defaultDur, nil
+	}
+	return time.ParseDuration(value)
+}
@@ -0,0 +1,145 @@
+package modules
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/astaxie/beego/logs"
+)
+
+// walkList 是需要翻页采集的一个列表, url 是第一页
+type walkList struct {
+	url     string
+	handler pageHandler
+}
+
+// walkItem 是表中的一行, 例如一个问题或一个用户, 它的列表按顺序采集
+type walkItem struct {
+	id    uint64
+	name  string
+	lists []walkList
+}
+
+// resumableWalk 按 id 顺序依次采集表中每一行的列表, 每个列表最多 MaxPages 页.
+// 进度记录下次开始采集的行, 列表和下一页, 中断后从这一页继续.
+type resumableWalk struct {
+	// name 用于日志
+	name string
+	// loadProgress 从未保存过进度时返回 sql.ErrNoRows, id 可以是还不存在的行
+	loadProgress func(ctx context.Context) (id uint64, list int, nextURL string, err error)
+	saveProgress func(ctx context.Context, id uint64, list int, nextURL string) error
+	// offset 返回 id 之前的行数, get 返回第 offset 行, 没有更多的行时返回 sql.ErrNoRows
+	offset func(ctx context.Context, id uint64) (uint64, error)
+	get    func(ctx context.Context, offset uint64) (*walkItem, error)
+	// byID 为 true 时 get 的参数是上一行的 id, 返回 id 更大的第一行, 此时不使用 offset.
+	// id 不连续时按行数遍历会重复采集, 例如 urlToken 表.
+	byID bool
+}
+
+func (zh *ZhiHu) walk(ctx context.Context, job *CollectJob, rw *resumableWalk) {
+	var offset uint64
+	id, list, startURL, err := rw.loadProgress(ctx)
+	if err == sql.ErrNoRows {
+		list, startURL = 0, ""
+	} else if err != nil {
+		logs.Error("error when get %s progress: %s", rw.name, err)
+		return
+	} else {
+		if rw.byID {
+			// 从进度中的行开始, 即使它已经被删除
+			if id > 0 {
+				offset = id - 1
+			}
+		} else if offset, err = rw.offset(ctx, id); err != nil {
+			logs.Error("error when get %s offset: %s", rw.name, err)
+			return
+		}
+		logs.Info("load %s progress success", rw.name)
+	}
+
+	// nextID 是下次开始采集的行, 可以是还不存在的 id
+	var nextID uint64
+loop:
+	for {
+		// 正常取消时不需要再查询下一行
+		if ctx.Err() != nil {
+			logs.Info("stop collect %s: %s", rw.name, ctx.Err())
+			break loop
+		}
+
+		item, err := rw.get(ctx, offset)
+		if err == sql.ErrNoRows {
+			logs.Info("no %s left to collect", rw.name)
+			break loop
+		} else if err != nil {
+			logs.Error("%s", err)
+			break loop
+		}
+
+		for ; list < len(item.lists); list++ {
+			if startURL == "" {
+				startURL = item.lists[list].url
+			}
+			nextURL, err := zh.continuePaging(ctx, job, startURL, job.config.MaxPages, item.lists[list].handler)
+			// 列表已经采集完时 err 为 nil, 即使 ctx 已经取消也继续下一个列表
+			if err != nil && ctx.Err() != nil {
+				// 下次从这一页继续
+				logs.Info("stop collect %s: %s", rw.name, err)
+				nextID = item.id
+				startURL = nextURL
+				break loop
+			} else if stopWalk(err) {
+				// 其他行也会出同样的错误, 保留进度, 解决后从这一页继续
+				logs.Error("stop collect %s: %s, url: %s, err: %s", rw.name, item.name, nextURL, err)
+				nextID = item.id
+				startURL = nextURL
+				break loop
+			} else if errors.Is(err, ErrGone) {
+				logs.Info("%s gone: %s", rw.name, item.name)
+			} else if err != nil {
+				logs.Error("error when collect %s: %s, url: %s, err: %s", rw.name, item.name, item.lists[list].url, err)
+			}
+			startURL = ""
+		}
+
+		nextID = item.id + 1
+		list = 0
+		if rw.byID {
+			offset = item.id
+		} else {
+			offset++
+		}
+	}
+
+	if nextID == 0 {
+		return
+	}
+
+	// ctx 取消后依然需要保存进度
+	if err := rw.saveProgress(context.Background(), nextID, list, startURL); err != nil {
+		logs.Error("error when insert %s progress: %s", rw.name, err)
+	}
+}
+
+// stopWalk 判断 err 是否需要人工处理 (登录失效, robots.txt 不允许, 一直被拦截), 这时跳过当前行没有意义
+func stopWalk(err error) bool {
+	return errors.Is(err, ErrNotLoggedIn) || errors.Is(err, ErrDisallowed) || errors.Is(err, ErrRateLimited)
+}
+
+// only 让 rw 只遍历 items, 每次运行都从第一个开始.
+// 不读取和保存进度, 避免和遍历整张表的进度混在一起.
+func (rw *resumableWalk) only(items []*walkItem) {
+	rw.loadProgress = func(ctx context.Context) (uint64, int, string, error) {
+		return 0, 0, "", sql.ErrNoRows
+	}
+	rw.saveProgress = func(ctx context.Context, id uint64, list int, nextURL string) error {
+		return nil
+	}
+	rw.get = func(ctx context.Context, offset uint64) (*walkItem, error) {
+		if offset >= uint64(len(items)) {
+			return nil, sql.ErrNoRows
+		}
+		return items[offset], nil
+	}
+}
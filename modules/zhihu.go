@@ -1,15 +1,18 @@
 package modules
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 	"zhihu/utils"
 
@@ -22,18 +25,16 @@ const (
 	collectTopicID
 	collectTopic
 	collectPeople
-)
-
-const (
-	FolloweeAPI    = `https://www.zhihu.com/api/v4/members/%s/followees?include=data[*].answer_count,articles_count,gender,follower_count,is_followed,is_following,badge[?(type=best_answerer)].topics&offset=0&limit=20`
-	FollowerAPI    = `https://www.zhihu.com/api/v4/members/%s/followers?include=data[*].answer_count,articles_count,gender,follower_count,is_followed,is_following,badge[?(type=best_answerer)].topics&offset=0&limit=20`
-	UserSumInfoAPI = `https://www.zhihu.com/api/v4/members/%s?include=allow_message,is_followed,is_following,is_org,is_blocking,employments,answer_count,follower_count,articles_count,gender,badge[?(type=best_answerer)].topics`
-	TopicIDAPI     = `https://www.zhihu.com/api/v3/topics/%s/children`
-	PeopleAPI      = `https://www.zhihu.com/people/%s/activities`
-)
-
-const (
-	TopicWebPage = `https://www.zhihu.com/topic/%s/hot`
+	collectPipeline
+	refreshPeople
+	collectQuestionAnswers
+	collectUserAnswers
+	collectUserArticles
+	collectColumnArticles
+	collectTopicQuestions
+	collectComments
+	collectHotList
+	collectSearch
 )
 
 const (
@@ -50,11 +51,17 @@ func NewZhiHu(config *Config) (*ZhiHu, error) {
 	mysqlConfig := config.MySQL
 	emailConfig := config.Email
 
-	cookies, err := utils.StringToCookies(zhiHuConfig.Cookie, ".zhihu.com")
+	api := zhiHuConfig.API
+	if api == nil {
+		api = &APIConfig{}
+	}
+	api.setDefault()
+
+	cookies, err := utils.StringToCookies(zhiHuConfig.Cookie, api.CookieDomain)
 	if err != nil {
 		return nil, err
 	}
-	cookieURL, err := url.Parse("https://www.zhihu.com")
+	cookieURL, err := url.Parse(api.BaseURL)
 	if err != nil {
 		return nil, err
 	}
@@ -65,8 +72,31 @@ func NewZhiHu(config *Config) (*ZhiHu, error) {
 	}
 	jar.SetCookies(cookieURL, cookies)
 
-	client := &http.Client{
-		Jar: jar,
+	httpConfig := config.HTTP
+	if httpConfig == nil {
+		httpConfig = &HTTPConfig{}
+	}
+	client, err := newClient(httpConfig)
+	if err != nil {
+		return nil, err
+	}
+	client.Jar = jar
+
+	minInterval, err := parseDuration(httpConfig.MinInterval, 0)
+	if err != nil {
+		return nil, err
+	}
+	limiter := newRateLimiter(minInterval)
+
+	var robots *robotsPolicy
+	if httpConfig.Robots != nil && httpConfig.Robots.Enable {
+		robotsClient, err := newRobotsClient(httpConfig)
+		if err != nil {
+			return nil, err
+		}
+		if robots, err = newRobotsPolicy(httpConfig.Robots, httpConfig.UserAgent, robotsClient, limiter); err != nil {
+			return nil, err
+		}
 	}
 
 	ds, err := NewDataSource(mysqlConfig)
@@ -82,18 +112,42 @@ func NewZhiHu(config *Config) (*ZhiHu, error) {
 		dur = pauseDurationLimit
 	}
 
+	coolDown, err := parseCoolDown(zhiHuConfig)
+	if err != nil {
+		return nil, err
+	}
+
 	emailSender, err := NewEmailSender(emailConfig)
 	if err != nil {
 		return nil, err
 	}
 
+	configs, err := jobConfigs(zhiHuConfig)
+	if err != nil {
+		return nil, err
+	}
+	var jobs []*CollectJob
+	for _, jc := range configs {
+		job, err := newJob(jc)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
 	zhiHu := &ZhiHu{
 		config:        zhiHuConfig,
+		api:           api,
 		pauseDuration: dur,
+		coolDown:      coolDown,
+		maxBodySize:   maxBodySize(httpConfig),
+		limiter:       limiter,
+		robots:        robots,
 		client:        client,
+		userAgent:     httpConfig.UserAgent,
 		emailSender:   emailSender,
 		dataSource:    ds,
-		stop:          make(chan struct{}),
+		jobs:          jobs,
 		stopFinish:    make(chan struct{}),
 	}
 	return zhiHu, nil
@@ -101,60 +155,91 @@ func NewZhiHu(config *Config) (*ZhiHu, error) {
 
 type ZhiHu struct {
 	config        *ZhiHuConfig
+	api           *APIConfig
 	pauseDuration time.Duration
-
-	client      *http.Client
-	dataSource  *DataSource
+	coolDown      *coolDown
+
+	client *http.Client
+	// userAgent 为空时使用浏览器的 User-Agent
+	userAgent   string
+	maxBodySize int64
+	limiter     *rateLimiter
+	// robots 为 nil 时不检查 robots.txt
+	robots      *robotsPolicy
+	dataSource  Store
 	emailSender *EmailSender
 
-	stop       chan struct{}
+	jobs []*CollectJob
+
+	cancel context.CancelFunc
+	// 所有任务结束后关闭
 	stopFinish chan struct{}
 }
 
-func (zh *ZhiHu) Start() {
-	switch zh.config.Mode {
-	case collectURLToken:
-		go zh.CollectURLToken()
-		go zh.sendURLTokenAmountRegularly()
-	case collectTopicID:
-		go zh.CollectTopicID()
-	case collectTopic:
-		go zh.CollectTopic()
-	case collectPeople:
-		go zh.CollectPeople()
-	default:
-		logs.Warn("unexpected start mode")
+// Start 在后台运行所有任务, parent 取消或调用 Stop 时所有请求都会被中断,
+// 调用 StopJob 时只中断对应的任务.
+func (zh *ZhiHu) Start(parent context.Context) {
+	ctx, cancel := context.WithCancel(parent)
+	zh.cancel = cancel
+
+	wg := &sync.WaitGroup{}
+	for _, job := range zh.jobs {
+		jobCtx := job.withCancel(ctx)
+		wg.Add(1)
+		go func(job *CollectJob, ctx context.Context) {
+			defer wg.Done()
+			zh.scheduleJob(ctx, job)
+		}(job, jobCtx)
+
+		// StopJob 时随任务一起停止
+		if mode := job.config.Mode; mode == collectURLToken || mode == collectPipeline {
+			go zh.sendURLTokenAmountRegularly(jobCtx)
+		}
 	}
-}
+	go zh.logProgressRegularly(ctx)
 
-func (zh *ZhiHu) CollectURLToken() {
-	defer func() {
+	go func() {
+		wg.Wait()
 		close(zh.stopFinish)
 	}()
+}
 
+func (zh *ZhiHu) CollectURLToken(ctx context.Context, job *CollectJob) {
 	ds := zh.dataSource
 
 	// 确保有一条数据
-	if err := ds.InsertURLTokens([]string{zh.config.OwnURLToken}); err != nil {
+	inserted, err := ds.InsertURLTokens(ctx, []*URLToken{{URLToken: zh.config.OwnURLToken}})
+	if err != nil {
 		logs.Error("%s", err)
 		return
 	}
+	job.enqueue(ctx, inserted)
+
+	// MaxNodes 只限制本次运行新插入的 urlToken, 已有的 urlToken 不计入
+	job.setNodes(uint64(len(inserted)))
+
+	if job.config.Priority != nil {
+		zh.collectURLTokenByPriority(ctx, job)
+		return
+	}
 
 	var offset uint64
+	// current 是正在展开的 urlToken
+	var current *URLToken
 	var startFolloweeURL string
 	var startFollowerURL string
 
 	// 初始化进度
-	utp, err := ds.GetURLTokenProgress()
+	utp, err := ds.GetURLTokenProgress(ctx)
 	if err == sql.ErrNoRows { // 从未保存过记录
 		offset = 0
-		startFolloweeURL = fmt.Sprintf(FolloweeAPI, zh.config.OwnURLToken)
-		startFollowerURL = fmt.Sprintf(FollowerAPI, zh.config.OwnURLToken)
+		startFolloweeURL = zh.api.FolloweeURL(zh.config.OwnURLToken)
+		startFollowerURL = zh.api.FollowerURL(zh.config.OwnURLToken)
 	} else if err != nil {
 		logs.Error("error when get urlTokenProgress: %s", err)
 		return
 	} else {
-		if offset, err = ds.GetURLTokenOffset(utp.URLTokenID); err != nil {
+		if offset, err = ds.GetURLTokenOffset(ctx, utp.URLTokenID); err != nil {
 			logs.Error("error when get urlTokenOffset: %s", err)
 			return
 		}
@@ -165,48 +250,74 @@ func (zh *ZhiHu) CollectURLToken() {
 		logs.Debug("load urlTokenProgress success")
 	}
 
+	next := offset
 loop:
 	for {
+		current, next, err = zh.unexpandedURLToken(ctx, next)
+		if err == sql.ErrNoRows {
+			logs.Info("url token get gone")
+			break loop
+		} else if err != nil {
+			logs.Error("%s", err)
+			break loop
+		}
+		if next != offset {
+			// 进度中的 urlToken 已经展开完成, 从下一个未展开的 urlToken 开始
+			offset = next
+			startFolloweeURL = zh.api.FolloweeURL(current.URLToken)
+			startFollowerURL = zh.api.FollowerURL(current.URLToken)
+		}
+
+		if job.reachMaxDepth(current.Depth) || job.reachMaxNodes() {
+			break loop
+		}
+
 		// 这里为 start* 赋值只是为了记录进度
-		startFolloweeURL = zh.continueGetFollowee(startFolloweeURL)
-		startFollowerURL = zh.continueGetFollowee(startFollowerURL)
+		startFolloweeURL, startFollowerURL, err = zh.expandURLToken(ctx, job, startFolloweeURL, startFollowerURL, current.Depth+1)
+		if job.reachMaxNodes() {
+			// 保存当前 urlToken 的进度, 提高 MaxNodes 后可以继续
+			break loop
+		}
 
 		select {
-		case <-zh.stop:
+		case <-ctx.Done():
 			logs.Info("stop collect url token, offset: %d", offset)
 			break loop
 		default:
 		}
 
-		offset++
-		urlToken, err := ds.GetURLToken(offset)
-		if err == sql.ErrNoRows {
-			logs.Info("url token get gone")
-			break loop
-		} else if err != nil {
-			logs.Error("%s", err)
+		// 用户已注销时不需要再展开, 其他错误保存进度, 下次从出错的页继续
+		if err != nil && !errors.Is(err, ErrGone) {
+			logs.Error("stop collect url token: %s, urlToken: %s", err, current.URLToken)
 			break loop
 		}
 
-		startFolloweeURL = fmt.Sprintf(FolloweeAPI, urlToken.URLToken)
-		startFollowerURL = fmt.Sprintf(FollowerAPI, urlToken.URLToken)
+		// 和按优先级展开共用 expanded 标记, 切换方式后不会重复展开
+		if err := ds.MarkURLTokenExpanded(ctx, current.ID); err != nil {
+			logs.Error("error when mark urlToken expanded: %s", err)
+			break loop
+		}
+		next = offset + 1
 	}
 
+	// ctx 取消后依然需要保存进度
+	ctx = context.Background()
+
 	// 保存进度
-	urlToken, err := ds.GetURLToken(offset)
+	urlToken, err := ds.GetURLToken(ctx, offset)
 	if err == sql.ErrNoRows {
 		urlToken = &URLToken{
 			ID: 1, // 从头开始?
 		}
-		startFolloweeURL = fmt.Sprintf(FolloweeAPI, zh.config.OwnURLToken)
-		startFollowerURL = fmt.Sprintf(FollowerAPI, zh.config.OwnURLToken)
+		startFolloweeURL = zh.api.FolloweeURL(zh.config.OwnURLToken)
+		startFollowerURL = zh.api.FollowerURL(zh.config.OwnURLToken)
 		logs.Warn("url token get gone when will save urlTokenProgress")
 	} else if err != nil {
 		urlToken = &URLToken{
 			ID: 1, // 从头开始?
 		}
-		startFolloweeURL = fmt.Sprintf(FolloweeAPI, zh.config.OwnURLToken)
-		startFollowerURL = fmt.Sprintf(FollowerAPI, zh.config.OwnURLToken)
+		startFolloweeURL = zh.api.FolloweeURL(zh.config.OwnURLToken)
+		startFollowerURL = zh.api.FollowerURL(zh.config.OwnURLToken)
 		logs.Error("url token get gone when will save urlTokenProgress: %s", err)
 	}
 
@@ -214,13 +325,30 @@ loop:
 	urlTokenProgress.URLTokenID = urlToken.ID
 	urlTokenProgress.NextFolloweeURL = startFolloweeURL
 	urlTokenProgress.NextFollowerURL = startFollowerURL
-	if err := ds.InsertURLTokenProgress(urlTokenProgress); err != nil {
+	if err := ds.InsertURLTokenProgress(ctx, urlTokenProgress); err != nil {
 		logs.Error("error when insert urlTokenProgress: %s", err)
 	}
 }
 
+// unexpandedURLToken 从 offset 开始查找第一个未展开的 urlToken, 返回它和它的 offset
+func (zh *ZhiHu) unexpandedURLToken(ctx context.Context, offset uint64) (*URLToken, uint64, error) {
+	for ; ; offset++ {
+		urlToken, err := zh.dataSource.GetURLToken(ctx, offset)
+		if err != nil {
+			return urlToken, offset, err
+		}
+
+		_, expanded, err := zh.dataSource.GetURLTokenByID(ctx, urlToken.ID)
+		if err != nil || !expanded {
+			return urlToken, offset, err
+		}
+	}
+}
+
 func (zh *ZhiHu) Stop() {
-	close(zh.stop)
+	if zh.cancel != nil {
+		zh.cancel()
+	}
 
 	select {
 	case <-zh.stopFinish:
@@ -234,37 +362,108 @@ func (zh *ZhiHu) Wait() <-chan struct{} {
 	return zh.stopFinish
 }
 
-func (zh *ZhiHu) continueGetFollowee(startURL string) string {
+// expandURLToken 按照任务的方向保存关注和粉丝, 返回下次继续的位置.
+// 返回错误时 urlToken 还没有展开完成, 不能标记为已展开.
+func (zh *ZhiHu) expandURLToken(ctx context.Context, job *CollectJob, followeeURL, followerURL string, depth int) (string, string, error) {
+	var err error
+	direction := job.config.Direction
+	if direction != directionFollower {
+		if followeeURL, err = zh.continueGetFollowee(ctx, job, followeeURL, depth, job.config.MaxFolloweePages); err != nil {
+			return followeeURL, followerURL, err
+		}
+	}
+	if direction != directionFollowee {
+		followerURL, err = zh.continueGetFollowee(ctx, job, followerURL, depth, job.config.MaxFollowerPages)
+	}
+	return followeeURL, followerURL, err
+}
+
+// continueGetFollowee 保存 startURL 开始的关注或粉丝, depth 是新 urlToken 的深度,
+// maxPages 为 0 时请求所有页. 请求或插入出错时返回错误, nextURL 是下次继续的位置.
+func (zh *ZhiHu) continueGetFollowee(ctx context.Context, job *CollectJob, startURL string, depth int, maxPages int) (string, error) {
 	var pf *PagingFollowee
 	var err error
 	var nextURL string
-	urlTokens := make([]string, 20)
+	pageURL := startURL
+	var pages int
+	// saveErr 是插入出错时的错误, 此时 err 为 nil
+	var saveErr error
+	urlTokens := make([]*URLToken, 20)
 	urlTokens = urlTokens[:0]
 
+	if job.allowNodes(1) == 0 {
+		return startURL, nil
+	}
+
 	ticker := time.NewTicker(zh.pauseDuration)
 	defer ticker.Stop()
 
-	// 如果接收到 stop 信号, 那么起码把当前 url 的数据获取完成再停止
+	// ctx 取消时会中断当前请求, 返回的 nextURL 就是下次继续的位置
 loop:
-	for pf, err = zh.getFolloweeOrFollower(startURL); err == nil && len(pf.Data) != 0; pf, err = zh.getFolloweeOrFollower(nextURL) {
+	for pf, err = zh.getFolloweeOrFollower(ctx, startURL); err == nil && len(pf.Data) != 0; pf, err = zh.getFolloweeOrFollower(ctx, nextURL) {
 		for _, follow := range pf.Data {
-			urlTokens = append(urlTokens, follow.URLToken)
+			urlTokens = append(urlTokens, &URLToken{
+				URLToken: follow.URLToken,
+				Depth:    depth,
+				Score:    job.score(follow, depth),
+			})
 		}
 
-		if err := zh.dataSource.InsertURLTokens(urlTokens); err != nil {
-			logs.Error("insert followee error: %s", err)
+		// 已存在的 urlToken 不计入 MaxNodes, 所以分批插入直到达到限制
+		rest := urlTokens
+		var insertErr error
+		for len(rest) != 0 {
+			allow := job.allowNodes(len(rest))
+			if allow == 0 {
+				break
+			}
+			var inserted []*URLToken
+			inserted, insertErr = zh.dataSource.InsertURLTokens(ctx, rest[:allow])
+			job.addNodes(len(inserted))
+			job.enqueue(ctx, inserted)
+			if insertErr != nil {
+				break
+			}
+			rest = rest[allow:]
 		}
+		job.addPage(len(urlTokens))
 		urlTokens = urlTokens[:0]
+
+		if insertErr != nil {
+			// 插入失败或被取消, 下次从这一页重新插入, 已插入的 urlToken 会被忽略
+			logs.Error("insert followee error: %s, pageURL: %s", insertErr, pageURL)
+			nextURL = pageURL
+			saveErr = insertErr
+			break loop
+		}
+		if len(rest) != 0 {
+			// 达到 MaxNodes, 下次从这一页继续, 已插入的 urlToken 会被忽略
+			nextURL = pageURL
+			break loop
+		}
 		nextURL = pf.Paging.Next
+		pageURL = nextURL
+
+		pages++
+		if maxPages > 0 && pages >= maxPages {
+			logs.Debug("reach max pages: %d, startURL: %s", maxPages, startURL)
+			break loop
+		}
 
 		select {
-		case <-zh.stop:
+		case <-ctx.Done():
 			logs.Warn("stop continue get followee or follower")
 			break loop
 		case <-ticker.C:
 		}
 	}
-	if err != nil {
+	if ctx.Err() != nil {
+		logs.Warn("stop continue get followee or follower: %s", err)
+		err = ctx.Err()
+	} else if errors.Is(err, ErrGone) || errors.Is(err, ErrDisallowed) {
+		// 用户已注销或被封禁, 或者 robots.txt 不允许访问, 不需要通知
+		logs.Info("skip followee or follower: %s", err)
+	} else if err != nil {
 		logs.Error("get followee error: %s, startURL: %s, nextURL: %s", err, startURL, nextURL)
 
 		// todo: 这是个未解决的错误, 需要在运行该程序时调试; 需要邮件通知
@@ -281,65 +480,56 @@ loop:
 	if nextURL == "" {
 		nextURL = startURL
 	}
-	return nextURL
+	if saveErr != nil && ctx.Err() == nil {
+		return nextURL, saveErr
+	}
+	return nextURL, err
 }
 
-func (zh *ZhiHu) getFolloweeOrFollower(url string) (*PagingFollowee, error) {
+func (zh *ZhiHu) getFolloweeOrFollower(ctx context.Context, url string) (*PagingFollowee, error) {
 	timer := time.NewTimer(zh.pauseDuration)
 	defer timer.Stop()
 
 	var retryCount int
 	for {
-		req, err := utils.NewRequestWithUserAgent("GET", url, nil)
-		if err != nil {
-			return nil, err
+		data, err := zh.get(ctx, url)
+		if err == nil {
+			pf := &PagingFollowee{}
+			if err = json.Unmarshal(data, pf); err == nil {
+				return pf, nil
+			}
+			logs.Error("error when get followee or follower, url: %s, data: %s, err: %s", url, data, err)
 		}
 
-		resp, err := zh.client.Do(req)
-		if err != nil {
+		// 只重试服务端错误和无法解析的响应, 其他错误交给调用方区分
+		if !errors.Is(err, ErrServer) && !strings.HasPrefix(err.Error(), "invalid character") {
 			return nil, err
 		}
 
-		data, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
+		// 第一次也统计到重试次数中
+		retryCount++
+		logs.Debug("get followee or follower retry count: %d", retryCount)
+		if retryCount >= retryCountLimit {
+			logs.Error("retry count over")
 			return nil, err
 		}
-		resp.Body.Close()
 
-		pf := &PagingFollowee{}
-		if err := json.Unmarshal(data, pf); err != nil {
-			logs.Error("error when get followee or follower, url: %s, data: %s, err: %s", url, data, err)
-
-			if strings.HasPrefix(err.Error(), "invalid character") {
-				// 第一次也统计到重试次数中
-				retryCount++
-				logs.Debug("get followee or follower retry count: %d", retryCount)
-				if retryCount >= retryCountLimit {
-					logs.Error("retry count over")
-					return nil, err
-				}
-
-				timer.Reset(zh.pauseDuration)
-				select {
-				case <-timer.C:
-				}
-			} else {
-				// 其他错误
-				return nil, err
-			}
-		} else {
-			return pf, nil
+		timer.Reset(zh.pauseDuration)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
 		}
 	}
 }
 
-func (zh *ZhiHu) sendURLTokenAmountRegularly() {
+func (zh *ZhiHu) sendURLTokenAmountRegularly(ctx context.Context) {
 	msg := &EmailMsg{
 		Subject: "URLToken Amount",
 	}
 
 	// 先发送一次数据
-	count, err := zh.dataSource.CountURLToken()
+	count, err := zh.dataSource.CountURLToken(ctx)
 	msg.Content = fmt.Sprintf("count: %d, err: %s", count, err)
 
 	if err := zh.emailSender.SendEmail(msg); err != nil {
@@ -349,14 +539,14 @@ func (zh *ZhiHu) sendURLTokenAmountRegularly() {
 	ticker := time.NewTicker(24 * time.Hour)
 	for {
 		select {
-		case <-zh.stop:
+		case <-ctx.Done():
 			// 立即释放
 			ticker.Stop()
 			return
 		case <-ticker.C:
 		}
 
-		count, err := zh.dataSource.CountURLToken()
+		count, err := zh.dataSource.CountURLToken(ctx)
 		msg.Content = fmt.Sprintf("count: %d, err: %s", count, err)
 
 		if err := zh.emailSender.SendEmail(msg); err != nil {
@@ -438,18 +628,19 @@ type Company struct {
 type SelfRecommend struct {
 }
 
-func (zh *ZhiHu) CollectTopicID() {
-	defer func() {
-		close(zh.stopFinish)
-	}()
-
+func (zh *ZhiHu) CollectTopicID(ctx context.Context, job *CollectJob) {
 	ds := zh.dataSource
 
+	if len(job.config.RootTopicIDs) != 0 {
+		zh.collectTopicSubtree(ctx, job)
+		return
+	}
+
 	rootTopicID := &TopicID{
 		TopicID: zh.config.RootTopicID,
 		Name:    "根话题", // 比较懒
 	}
-	if err := ds.InsertTopicsID([]*TopicID{rootTopicID}); err != nil {
+	if err := ds.InsertTopicsID(ctx, []*TopicID{rootTopicID}); err != nil {
 		logs.Error("%s", err)
 		return
 	}
@@ -457,15 +648,15 @@ func (zh *ZhiHu) CollectTopicID() {
 	var offset uint64
 	var startTopicIDURL string
 
-	tip, err := ds.GetTopicIDProgress()
+	tip, err := ds.GetTopicIDProgress(ctx)
 	if err == sql.ErrNoRows {
 		offset = 0
-		startTopicIDURL = fmt.Sprintf(TopicIDAPI, zh.config.RootTopicID)
+		startTopicIDURL = zh.api.TopicIDURL(zh.config.RootTopicID)
 	} else if err != nil {
 		logs.Error("%s", err)
 		return
 	} else {
-		if offset, err = ds.GetTopicIDOffset(tip.TopicID); err != nil {
+		if offset, err = ds.GetTopicIDOffset(ctx, tip.TopicID); err != nil {
 			logs.Error("error when get topicIDOffset: %s", err)
 			return
 		}
@@ -476,17 +667,17 @@ func (zh *ZhiHu) CollectTopicID() {
 
 loop:
 	for {
-		startTopicIDURL = zh.continueGetTopicID(startTopicIDURL)
+		startTopicIDURL = zh.continueGetTopicID(ctx, job, startTopicIDURL)
 
 		select {
-		case <-zh.stop:
+		case <-ctx.Done():
 			logs.Info("stop collect topic id, offset: %d", offset)
 			break loop
 		default:
 		}
 
 		offset++
-		topicID, err := ds.GetTopicID(offset)
+		topicID, err := ds.GetTopicID(ctx, offset)
 		if err == sql.ErrNoRows {
 			logs.Info("topic id get gone")
 			break loop
@@ -495,21 +686,24 @@ loop:
 			break loop
 		}
 
-		startTopicIDURL = fmt.Sprintf(TopicIDAPI, topicID.TopicID)
+		startTopicIDURL = zh.api.TopicIDURL(topicID.TopicID)
 	}
 
-	topicID, err := ds.GetTopicID(offset)
+	// ctx 取消后依然需要保存进度
+	ctx = context.Background()
+
+	topicID, err := ds.GetTopicID(ctx, offset)
 	if err == sql.ErrNoRows {
 		topicID = &TopicID{
 			ID: 1,
 		}
-		startTopicIDURL = fmt.Sprintf(TopicIDAPI, zh.config.RootTopicID)
+		startTopicIDURL = zh.api.TopicIDURL(zh.config.RootTopicID)
 		logs.Warn("topic id get gone when will save topicIDProgress")
 	} else if err != nil {
 		topicID = &TopicID{
 			ID: 1,
 		}
-		startTopicIDURL = fmt.Sprintf(TopicIDAPI, zh.config.RootTopicID)
+		startTopicIDURL = zh.api.TopicIDURL(zh.config.RootTopicID)
 		logs.Warn("topic id get gone when will save topicIDProgress: %s", err)
 	}
 
@@ -517,12 +711,12 @@ loop:
 		TopicID:        topicID.ID,
 		NextTopicIDURL: startTopicIDURL,
 	}
-	if err := ds.InsertTopicIDProgress(topicIDProgress); err != nil {
+	if err := ds.InsertTopicIDProgress(ctx, topicIDProgress); err != nil {
 		logs.Error("error when insert topicIDProgress: %s", err)
 	}
 }
 
-func (zh *ZhiHu) continueGetTopicID(startTopicIDURL string) string {
+func (zh *ZhiHu) continueGetTopicID(ctx context.Context, job *CollectJob, startTopicIDURL string) string {
 	var pt *PagingTopic
 	var err error
 	var nextURL string
@@ -533,7 +727,7 @@ func (zh *ZhiHu) continueGetTopicID(startTopicIDURL string) string {
 	defer ticker.Stop()
 
 loop:
-	for pt, err = zh.getTopicID(startTopicIDURL); err == nil && len(pt.Data) != 0; pt, err = zh.getTopicID(nextURL) {
+	for pt, err = zh.getTopicID(ctx, startTopicIDURL); err == nil && len(pt.Data) != 0; pt, err = zh.getTopicID(ctx, nextURL) {
 		for _, topic := range pt.Data {
 			topicID := &TopicID{
 				TopicID: topic.ID,
@@ -542,14 +736,15 @@ loop:
 			topicsID = append(topicsID, topicID)
 		}
 
-		if err := zh.dataSource.InsertTopicsID(topicsID); err != nil {
+		if err := zh.dataSource.InsertTopicsID(ctx, topicsID); err != nil {
 			logs.Error("%s", err)
 		}
+		job.addPage(len(pt.Data))
 		topicsID = topicsID[0:]
 		nextURL = pt.Paging.Next
 
 		select {
-		case <-zh.stop:
+		case <-ctx.Done():
 			logs.Warn("stop continue get topicID")
 			break loop
 		case <-ticker.C:
@@ -565,47 +760,37 @@ loop:
 	return nextURL
 }
 
-func (zh *ZhiHu) getTopicID(topicIDURL string) (*PagingTopic, error) {
+func (zh *ZhiHu) getTopicID(ctx context.Context, topicIDURL string) (*PagingTopic, error) {
 	timer := time.NewTimer(zh.pauseDuration)
 	defer timer.Stop()
 
 	var retryCount int
 	for {
-		req, err := utils.NewRequestWithUserAgent("GET", topicIDURL, nil)
-		if err != nil {
-			return nil, err
+		data, err := zh.get(ctx, topicIDURL)
+		if err == nil {
+			pt := &PagingTopic{}
+			if err = json.Unmarshal(data, pt); err == nil {
+				return pt, nil
+			}
+			logs.Error("error when get topic id: url: %s, data: %s, err: %s", topicIDURL, data, err)
 		}
 
-		resp, err := zh.client.Do(req)
-		if err != nil {
+		if !errors.Is(err, ErrServer) && !strings.HasPrefix(err.Error(), "invalid character") {
 			return nil, err
 		}
 
-		data, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
+		retryCount++
+		logs.Debug("get topic id retry count: %d: ", retryCount)
+		if retryCount >= retryCountLimit {
+			logs.Error("retry count over")
 			return nil, err
 		}
-		resp.Body.Close()
 
-		pt := &PagingTopic{}
-		if err := json.Unmarshal(data, pt); err != nil {
-			logs.Error("error when get topic id: url: %s, data: %s, err: %s", topicIDURL, data, err)
-
-			if strings.HasPrefix(err.Error(), "invalid character") {
-				retryCount++
-				logs.Debug("get topic id retry count: %d: ", retryCount)
-				if retryCount >= retryCountLimit {
-					logs.Error("retry count over")
-					return nil, err
-				}
-
-				timer.Reset(zh.pauseDuration)
-				select {
-				case <-timer.C:
-				}
-			}
-		} else {
-			return pt, nil
+		timer.Reset(zh.pauseDuration)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
 		}
 	}
 }
@@ -615,6 +800,17 @@ type PagingTopic struct {
 	Data   []*Topic `json:"data"`
 }
 
+// TopicInfo 是话题接口返回的话题详情
+type TopicInfo struct {
+	ID                 string `json:"id"`
+	Name               string `json:"name"`
+	Introduction       string `json:"introduction"`
+	AvatarURL          string `json:"avatar_url"`
+	FollowersCount     uint64 `json:"followers_count"`
+	QuestionsCount     uint64 `json:"questions_count"`
+	BestAnswerersCount uint64 `json:"best_answerers_count"`
+}
+
 type Topic struct {
 	IsBlack      bool   `json:"is_black"`
 	Name         string `json:"name"`
@@ -627,20 +823,16 @@ type Topic struct {
 	ID           string `json:"id"`
 }
 
-func (zh *ZhiHu) CollectTopic() {
-	defer func() {
-		close(zh.stopFinish)
-	}()
-
+func (zh *ZhiHu) CollectTopic(ctx context.Context, job *CollectJob) {
 	var offset uint64
-	tp, err := zh.dataSource.GetTopicProgress()
+	tp, err := zh.dataSource.GetTopicProgress(ctx)
 	if err == sql.ErrNoRows {
 		offset = 0
 	} else if err != nil {
 		logs.Error("%s", err)
 		return
 	} else {
-		if offset, err = zh.dataSource.GetTopicIDOffset(tp.TopicID); err != nil {
+		if offset, err = zh.dataSource.GetTopicIDOffset(ctx, tp.TopicID); err != nil {
 			logs.Error("%s", err)
 			return
 		}
@@ -653,13 +845,13 @@ func (zh *ZhiHu) CollectTopic() {
 loop:
 	for {
 		select {
-		case <-zh.stop:
+		case <-ctx.Done():
 			logs.Info("stop collect topic")
 			break loop
 		case <-ticker.C:
 		}
 
-		ti, err := zh.dataSource.GetTopicID(offset)
+		ti, err := zh.dataSource.GetTopicID(ctx, offset)
 		if err == sql.ErrNoRows {
 			logs.Info("topic id get gone when collect topic")
 			break loop
@@ -668,15 +860,23 @@ loop:
 			break loop
 		}
 
-		startTopicURL := fmt.Sprintf(TopicWebPage, ti.TopicID)
-		if err := zh.crawlTopic(startTopicURL, ti.ID); err != nil {
-			logs.Error("error when crawlTopic, url: %s, topic id: %d err: %s",
-				startTopicURL, ti.ID, err)
+		if err := zh.crawlTopic(ctx, ti); ctx.Err() != nil {
+			// 请求被中断, 下次从该话题继续
+			logs.Info("stop collect topic: %s", err)
+			break loop
+		} else if err != nil {
+			logs.Error("error when crawlTopic, topicID: %s, id: %d err: %s",
+				ti.TopicID, ti.ID, err)
+		} else {
+			job.addPage(1)
 		}
 		offset++
 	}
 
-	ti, err := zh.dataSource.GetTopicID(offset)
+	// ctx 取消后依然需要保存进度
+	ctx = context.Background()
+
+	ti, err := zh.dataSource.GetTopicID(ctx, offset)
 	if err == sql.ErrNoRows {
 		ti = &TopicID{
 			ID: 1,
@@ -692,26 +892,76 @@ loop:
 	topicProgress := &TopicProgress{
 		TopicID: ti.ID,
 	}
-	if err := zh.dataSource.InsertTopicProgress(topicProgress); err != nil {
+	if err := zh.dataSource.InsertTopicProgress(ctx, topicProgress); err != nil {
 		logs.Error("error when insert topicProgress: %s", err)
 	}
 }
 
-func (zh *ZhiHu) crawlTopic(webPageURL string, id uint64) error {
-	// todo: 是否有重试逻辑
-	req, err := utils.NewRequestWithUserAgent("GET", webPageURL, nil)
+func (zh *ZhiHu) crawlTopic(ctx context.Context, ti *TopicID) error {
+	tt, err := zh.fetchTopicDetail(ctx, ti)
 	if err != nil {
 		return err
 	}
-	resp, err := zh.client.Do(req)
+	return zh.dataSource.InsertTopic(ctx, tt)
+}
+
+// fetchTopicDetail 优先使用话题接口, 接口出错时从话题页面采集关注者数和问题数
+func (zh *ZhiHu) fetchTopicDetail(ctx context.Context, ti *TopicID) (*TopicTable, error) {
+	tt, err := zh.fetchTopicInfo(ctx, ti.TopicID, ti.ID)
+	if err == nil || ctx.Err() != nil {
+		return tt, err
+	}
+	// 话题不存在, 未登录或被拦截时页面也无法访问, 只在接口出错或格式变化时使用页面
+	if !errors.Is(err, ErrServer) && !errors.Is(err, ErrUnexpected) {
+		return nil, err
+	}
+	logs.Warn("error when get topic from api, use web page instead: %s", err)
+
+	tt, err = zh.fetchTopic(ctx, zh.api.TopicWebPageURL(ti.TopicID), ti.ID)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer resp.Body.Close()
+	tt.Name = ti.Name
+	return tt, nil
+}
 
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+func (zh *ZhiHu) fetchTopicInfo(ctx context.Context, topicID string, id uint64) (*TopicTable, error) {
+	data, err := zh.get(ctx, zh.api.TopicURL(topicID))
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	ti := &TopicInfo{}
+	if err := json.Unmarshal(data, ti); err != nil {
+		return nil, fmt.Errorf("%w, parse topic, topicID: %s, err: %s", ErrUnexpected, topicID, err)
+	}
+	if ti.ID != topicID {
+		return nil, fmt.Errorf("%w, topicID: %s, got: %s", ErrUnexpected, topicID, ti.ID)
+	}
+
+	tt := &TopicTable{
+		TopicID:            id,
+		FollowerCount:      ti.FollowersCount,
+		QuestionCount:      ti.QuestionsCount,
+		Name:               ti.Name,
+		Introduction:       ti.Introduction,
+		AvatarURL:          ti.AvatarURL,
+		BestAnswerersCount: ti.BestAnswerersCount,
+	}
+	return tt, nil
+}
+
+// fetchTopic 从话题页面中解析关注者数和问题数
+func (zh *ZhiHu) fetchTopic(ctx context.Context, webPageURL string, id uint64) (*TopicTable, error) {
+	// todo: 是否有重试逻辑
+	data, err := zh.get(ctx, webPageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
 	}
 
 	tt := &TopicTable{
@@ -722,7 +972,7 @@ func (zh *ZhiHu) crawlTopic(webPageURL string, id uint64) error {
 	if value, ok := selection.First().Attr("title"); ok {
 		count, err := strconv.ParseUint(value, 10, 64)
 		if err != nil {
-			return fmt.Errorf("error when strconv topic follower count, topicID: %d, title: %s, err: %s",
+			return nil, fmt.Errorf("error when strconv topic follower count, topicID: %d, title: %s, err: %s",
 				id, value, err)
 		}
 		tt.FollowerCount = count
@@ -731,31 +981,27 @@ func (zh *ZhiHu) crawlTopic(webPageURL string, id uint64) error {
 	if value, ok := selection.Last().Attr("title"); ok {
 		count, err := strconv.ParseUint(value, 10, 64)
 		if err != nil {
-			return fmt.Errorf("error when strconv topic question count, topicID: %d, title: %s, err: %s",
+			return nil, fmt.Errorf("error when strconv topic question count, topicID: %d, title: %s, err: %s",
 				id, value, err)
 		}
 		tt.QuestionCount = count
 	}
 
-	return zh.dataSource.InsertTopic(tt)
+	return tt, nil
 }
 
-func (zh *ZhiHu) CollectPeople() {
-	defer func() {
-		close(zh.stopFinish)
-	}()
-
+func (zh *ZhiHu) CollectPeople(ctx context.Context, job *CollectJob) {
 	ds := zh.dataSource
 
 	var offset uint64
-	pp, err := ds.GetPeopleProgress()
+	pp, err := ds.GetPeopleProgress(ctx)
 	if err == sql.ErrNoRows {
 		offset = 0
 	} else if err != nil {
 		logs.Error("%s", err)
 		return
 	} else {
-		if offset, err = ds.GetURLTokenOffset(pp.URLTokenID); err != nil {
+		if offset, err = ds.GetURLTokenOffset(ctx, pp.URLTokenID); err != nil {
 			logs.Error("%s", err)
 			return
 		}
@@ -768,13 +1014,13 @@ func (zh *ZhiHu) CollectPeople() {
 loop:
 	for {
 		select {
-		case <-zh.stop:
+		case <-ctx.Done():
 			logs.Info("stop collect people")
 			break loop
 		case <-ticker.C:
 		}
 
-		ut, err := ds.GetURLToken(offset)
+		ut, err := ds.GetURLToken(ctx, offset)
 		if err == sql.ErrNoRows {
 			logs.Info("urlToken get gone when collect people")
 			break loop
@@ -783,14 +1029,25 @@ loop:
 			break loop
 		}
 
-		if err := zh.crawlPeople(ut.ID, ut.URLToken); err != nil {
+		if err := zh.crawlPeople(ctx, ut.ID, ut.URLToken); ctx.Err() != nil {
+			// 请求被中断, 下次从该用户继续
+			logs.Info("stop collect people: %s", err)
+			break loop
+		} else if errors.Is(err, ErrGone) {
+			logs.Info("people gone, urlToken: %s", ut.URLToken)
+		} else if err != nil {
 			logs.Error("error when crawlPeople, urlToken: %s, err: %s",
 				ut.URLToken, err)
+		} else {
+			job.addPage(1)
 		}
 		offset++
 	}
 
-	ut, err := ds.GetURLToken(offset)
+	// ctx 取消后依然需要保存进度
+	ctx = context.Background()
+
+	ut, err := ds.GetURLToken(ctx, offset)
 	if err == sql.ErrNoRows {
 		ut = &URLToken{
 			ID: 1,
@@ -806,28 +1063,32 @@ loop:
 	pp = &PeopleProgress{
 		URLTokenID: ut.ID,
 	}
-	if err := ds.InsertPeopleProgress(pp); err != nil {
+	if err := ds.InsertPeopleProgress(ctx, pp); err != nil {
 		logs.Error("error when insert peopleProgress: %s", err)
 	}
 }
 
-func (zh *ZhiHu) crawlPeople(urlTokenID uint64, urlToken string) error {
-	// todo: 是否有重试逻辑
-	urlTokenURL := fmt.Sprintf(PeopleAPI, urlToken)
-	req, err := utils.NewRequestWithUserAgent("GET", urlTokenURL, nil)
+func (zh *ZhiHu) crawlPeople(ctx context.Context, urlTokenID uint64, urlToken string) error {
+	people, err := zh.fetchPeople(ctx, urlToken)
 	if err != nil {
 		return err
 	}
+	people.URLTokenID = urlTokenID
 
-	resp, err := zh.client.Do(req)
+	return zh.dataSource.InsertPeople(ctx, people)
+}
+
+// fetchPeople 从个人主页的 js-initialData 中解析用户信息
+func (zh *ZhiHu) fetchPeople(ctx context.Context, urlToken string) (*People, error) {
+	// todo: 是否有重试逻辑
+	data, err := zh.get(ctx, zh.api.PeopleURL(urlToken))
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(data))
 	if err != nil {
-		return err
+		return nil, err
 	}
 	selection := doc.Find("#js-initialData")
 	script := selection.Text()
@@ -836,22 +1097,20 @@ func (zh *ZhiHu) crawlPeople(urlTokenID uint64, urlToken string) error {
 	startKeyLen := len(startKey)
 	startIndex := strings.Index(script, startKey)
 	if startIndex < 0 {
-		return fmt.Errorf("not found script start index: %s", urlToken)
+		return nil, fmt.Errorf("not found script start index: %s", urlToken)
 	}
 
 	endKey := fmt.Sprintf(`,"%s"`, "selfRecommend")
 	endIndex := strings.Index(script, endKey)
 	if endIndex < 0 {
-		return fmt.Errorf("not found script end index: %s", urlToken)
+		return nil, fmt.Errorf("not found script end index: %s", urlToken)
 	}
 
 	script = script[startIndex+startKeyLen:endIndex] + "}"
 
 	people := &People{}
 	if err := json.Unmarshal([]byte(script), people); err != nil {
-		return err
+		return nil, err
 	}
-	people.URLTokenID = urlTokenID
-
-	return zh.dataSource.InsertPeople(people)
+	return people, nil
 }